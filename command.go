@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+// resourceLabelInvalidChars matches characters not allowed in a resource name.
+var resourceLabelInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// Standard streams of the commands, replaced in tests.
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// newClient returns the AWS client for a configuration, replaced in tests.
+var newClient = func(config *encryptedssm.Config) (interface{}, error) {
+	return config.Client()
+}
+
+// commands are run in place of the plugin server when their name is the first
+// argument, returning the exit status.
+var commands = map[string]func(args []string) int{
+	"decrypt": runDecrypt,
+	"encrypt": runEncrypt,
+	"migrate": runMigrate,
+	"rotate":  runRotate,
+}
+
+// cipherFlags are the flags shared by the encrypt and decrypt commands,
+// named after the matching encryptedssm_parameter arguments.
+type cipherFlags struct {
+	region  string
+	profile string
+
+	key       string
+	algorithm string
+	context   contextFlag
+	name      string
+	bindName  bool
+}
+
+func (f *cipherFlags) register(fs *flag.FlagSet) {
+	f.context = make(contextFlag)
+
+	fs.StringVar(&f.region, "region", defaultRegion(), "AWS region, defaults to AWS_REGION or AWS_DEFAULT_REGION")
+	fs.StringVar(&f.profile, "profile", "", "AWS shared credentials profile")
+	fs.StringVar(&f.key, "key", "", "KMS key ID, ARN or alias the value is encrypted with")
+	fs.StringVar(&f.algorithm, "algorithm", "", "encryption algorithm, RSAES_OAEP_SHA_1 or RSAES_OAEP_SHA_256 for asymmetric keys")
+	fs.Var(f.context, "context", "encryption context as KEY=VALUE, may be repeated")
+	fs.StringVar(&f.name, "name", "", "SSM parameter name")
+	fs.BoolVar(&f.bindName, "bind-name", false, "add the parameter name to the encryption context, as bind_name_context")
+}
+
+func (f *cipherFlags) validate() error {
+	if f.key == "" {
+		return fmt.Errorf("-key is required")
+	}
+
+	if f.bindName && f.name == "" {
+		return fmt.Errorf("-bind-name requires -name")
+	}
+
+	return nil
+}
+
+// options returns the KMS options for the flags, binding the parameter name
+// into the encryption context as the provider does.
+func (f *cipherFlags) options() encryptedssm.CipherOptions {
+	context := make(map[string]string, len(f.context)+1)
+	for k, v := range f.context {
+		context[k] = v
+	}
+
+	if f.bindName {
+		context[encryptedssm.ParameterNameContextKey] = f.name
+	}
+
+	return encryptedssm.CipherOptions{
+		KeyId:               f.key,
+		EncryptionAlgorithm: f.algorithm,
+		EncryptionContext:   context,
+	}
+}
+
+// client returns an AWS client configured through the same credential chain
+// as the provider: flags, environment, shared credentials and instance roles.
+func (f *cipherFlags) client() (interface{}, error) {
+	config := encryptedssm.Config{
+		Profile:    f.profile,
+		Region:     f.region,
+		MaxRetries: 25,
+
+		SkipRequestingAccountId: true,
+	}
+
+	return newClient(&config)
+}
+
+// defaultRegion returns the region from the environment, as the provider's
+// region argument defaults to.
+func defaultRegion() string {
+	if v := os.Getenv("AWS_REGION"); v != "" {
+		return v
+	}
+
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// contextFlag collects repeated KEY=VALUE flags.
+type contextFlag map[string]string
+
+func (c contextFlag) String() string {
+	pairs := make([]string, 0, len(c))
+	for k, v := range c {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (c contextFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+
+	c[parts[0]] = parts[1]
+
+	return nil
+}
+
+// readInput reads the named file, or standard input when path is empty or -.
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return ioutil.ReadAll(stdin)
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+func commandError(err error) int {
+	fmt.Fprintf(stderr, "Error: %s\n", err)
+	return 1
+}
+
+// blockAttribute is an argument of a generated resource block.
+type blockAttribute struct {
+	name  string
+	value cty.Value
+}
+
+// appendParameterBlock appends an encryptedssm_parameter resource block to
+// body with the given arguments in order, map arguments last and each set
+// apart by a blank line as terraform fmt lays them out.
+func appendParameterBlock(body *hclwrite.Body, label string, attrs []blockAttribute) {
+	blockBody := body.AppendNewBlock("resource", []string{"encryptedssm_parameter", label}).Body()
+
+	var maps []blockAttribute
+	for _, attr := range attrs {
+		if attr.value.Type().IsMapType() {
+			maps = append(maps, attr)
+			continue
+		}
+
+		blockBody.SetAttributeValue(attr.name, attr.value)
+	}
+
+	for _, attr := range maps {
+		blockBody.AppendNewline()
+		blockBody.SetAttributeValue(attr.name, attr.value)
+	}
+}
+
+// parameterResourceLabel derives a resource name from a parameter name, for
+// example /app/db/password becomes app_db_password.
+func parameterResourceLabel(name string) string {
+	label := strings.Trim(resourceLabelInvalidChars.ReplaceAllString(name, "_"), "_")
+
+	if label == "" || (label[0] >= '0' && label[0] <= '9') || label[0] == '-' {
+		label = "parameter_" + label
+	}
+
+	return label
+}
+
+// stringMapVal returns a map of strings as a cty map value.
+func stringMapVal(m map[string]string) cty.Value {
+	if len(m) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+
+	values := make(map[string]cty.Value, len(m))
+	for k, v := range m {
+		values[k] = cty.StringVal(v)
+	}
+
+	return cty.MapVal(values)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const decryptUsage = `Usage: terraform-provider-encryptedssm decrypt -key KEY [options] [CIPHERTEXT]
+
+  Decrypts an encrypted_value, given as an argument or read from standard
+  input, and writes the plaintext to standard output. The key, algorithm and
+  encryption context must match those the value was encrypted with.
+
+Options:
+`
+
+func runDecrypt(args []string) int {
+	var cf cipherFlags
+
+	fs := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), decryptUsage)
+		fs.PrintDefaults()
+	}
+
+	cf.register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := cf.validate(); err != nil {
+		return commandError(err)
+	}
+
+	var ciphertext string
+	switch fs.NArg() {
+	case 0:
+		input, err := readInput("")
+		if err != nil {
+			return commandError(fmt.Errorf("error reading ciphertext: %s", err))
+		}
+
+		ciphertext = string(input)
+	case 1:
+		ciphertext = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	client, err := cf.client()
+	if err != nil {
+		return commandError(err)
+	}
+
+	plaintext, err := encryptedssm.DecryptValue(client, cf.options(), strings.TrimSpace(ciphertext))
+	if err != nil {
+		return commandError(err)
+	}
+
+	stdout.Write(plaintext)
+
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunDecrypt(t *testing.T) {
+	testCommandKMS(t)
+
+	code, out, errOut := testRunCommand(t, runEncrypt, "MyStr0ngp@ss!",
+		"-region", "us-east-1", "-key", "alias/test", "-name", "password", "-context", "environment=prod")
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	_, attrs := testParseParameterBlock(t, out)
+	encryptedValue := attrs["encrypted_value"].AsString()
+
+	testCases := []struct {
+		input    string
+		args     []string
+		code     int
+		expected string
+	}{
+		{"", []string{"-context", "environment=prod", encryptedValue}, 0, ""},
+		{"  " + encryptedValue + "\n", []string{"-context", "environment=prod"}, 0, ""},
+		{"", []string{encryptedValue}, 1, "InvalidCiphertextException"},
+		{"", []string{"-context", "environment=dev", encryptedValue}, 1, "InvalidCiphertextException"},
+		{"", []string{"-context", "environment=prod", "not base64!"}, 1, "Error:"},
+		{"", []string{"-context", "environment=prod", encryptedValue, encryptedValue}, 2, "Usage:"},
+	}
+
+	for i, tc := range testCases {
+		code, out, errOut := testRunCommand(t, runDecrypt, tc.input, append([]string{"-region", "us-east-1", "-key", "alias/test"}, tc.args...)...)
+
+		if code != tc.code || !strings.Contains(errOut, tc.expected) {
+			t.Errorf("case %d: expected exit status %d and error %q, got %d: %s", i, tc.code, tc.expected, code, errOut)
+			continue
+		}
+
+		if code == 0 && out != "MyStr0ngp@ss!" {
+			t.Errorf("case %d: expected the plaintext as output, got %q", i, out)
+		}
+
+		if code != 0 && out != "" {
+			t.Errorf("case %d: expected no output, got %q", i, out)
+		}
+	}
+
+	if code, _, errOut := testRunCommand(t, runDecrypt, "", "-region", "us-east-1", "-key", "alias/other",
+		"-context", "environment=prod", encryptedValue); code != 1 || !strings.Contains(errOut, "IncorrectKeyException") {
+		t.Fatalf("expected decrypting with another key to fail, got exit status %d: %s", code, errOut)
+	}
+
+	if code, _, errOut := testRunCommand(t, runDecrypt, "", encryptedValue); code != 1 || !strings.Contains(errOut, "-key is required") {
+		t.Fatalf("expected -key to be required, got exit status %d: %s", code, errOut)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const encryptUsage = `Usage: terraform-provider-encryptedssm encrypt -key KEY -name NAME [options]
+
+  Encrypts a value read from standard input, or -in, and writes an
+  encryptedssm_parameter resource block for it to standard output.
+
+  A single trailing newline is removed from the value unless -keep-newline is
+  set. With -public-key the value is encrypted locally with the public key of
+  an asymmetric KMS key and no AWS credentials are needed.
+
+Options:
+`
+
+func runEncrypt(args []string) int {
+	var cf cipherFlags
+	var in, publicKey, ssmKey, resourceLabel, parameterType string
+	var keepNewline bool
+
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), encryptUsage)
+		fs.PrintDefaults()
+	}
+
+	cf.register(fs)
+	fs.StringVar(&in, "in", "", "file to read the value from instead of standard input")
+	fs.StringVar(&publicKey, "public-key", "", "PEM or DER public key file of an asymmetric KMS key to encrypt with locally")
+	fs.StringVar(&ssmKey, "ssm-key", "", "symmetric KMS key SSM stores the parameter with, as ssm_key_id, required with asymmetric keys")
+	fs.StringVar(&resourceLabel, "resource", "", "resource name in the generated block, derived from -name by default")
+	fs.StringVar(&parameterType, "type", "SecureString", "parameter type, SecureString or String")
+	fs.BoolVar(&keepNewline, "keep-newline", false, "keep a trailing newline in the value")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := cf.validate(); err != nil {
+		return commandError(err)
+	}
+
+	if cf.name == "" {
+		return commandError(fmt.Errorf("-name is required"))
+	}
+
+	if parameterType != "SecureString" && parameterType != "String" {
+		return commandError(fmt.Errorf("-type must be SecureString or String, got %q", parameterType))
+	}
+
+	plaintext, err := readInput(in)
+	if err != nil {
+		return commandError(fmt.Errorf("error reading value: %s", err))
+	}
+
+	if !keepNewline {
+		plaintext = []byte(strings.TrimSuffix(strings.TrimSuffix(string(plaintext), "\n"), "\r"))
+	}
+
+	opts := cf.options()
+
+	if publicKey != "" && opts.EncryptionAlgorithm == "" {
+		opts.EncryptionAlgorithm = kms.EncryptionAlgorithmSpecRsaesOaepSha256
+	}
+
+	if ssmKey == "" && opts.EncryptionAlgorithm != "" && opts.EncryptionAlgorithm != kms.EncryptionAlgorithmSpecSymmetricDefault {
+		return commandError(fmt.Errorf("-ssm-key is required with encryption algorithm %s", opts.EncryptionAlgorithm))
+	}
+
+	var encryptedValue string
+	if publicKey != "" {
+		if len(opts.EncryptionContext) > 0 {
+			return commandError(fmt.Errorf("encryption context is not supported by asymmetric keys"))
+		}
+
+		key, err := ioutil.ReadFile(publicKey)
+		if err != nil {
+			return commandError(fmt.Errorf("error reading public key: %s", err))
+		}
+
+		encryptedValue, err = encryptedssm.EncryptValueWithPublicKey(key, opts.EncryptionAlgorithm, plaintext)
+		if err != nil {
+			return commandError(err)
+		}
+	} else {
+		client, err := cf.client()
+		if err != nil {
+			return commandError(err)
+		}
+
+		encryptedValue, err = encryptedssm.EncryptValue(client, opts, plaintext)
+		if err != nil {
+			return commandError(err)
+		}
+	}
+
+	if resourceLabel == "" {
+		resourceLabel = parameterResourceLabel(cf.name)
+	}
+
+	attrs := []blockAttribute{
+		{"name", cty.StringVal(cf.name)},
+		{"type", cty.StringVal(parameterType)},
+		{"encryption_key", cty.StringVal(cf.key)},
+	}
+
+	if opts.EncryptionAlgorithm != "" {
+		attrs = append(attrs, blockAttribute{"encryption_algorithm", cty.StringVal(opts.EncryptionAlgorithm)})
+	}
+
+	if ssmKey != "" {
+		attrs = append(attrs, blockAttribute{"ssm_key_id", cty.StringVal(ssmKey)})
+	}
+
+	attrs = append(attrs, blockAttribute{"encrypted_value", cty.StringVal(encryptedValue)})
+
+	if cf.bindName {
+		attrs = append(attrs, blockAttribute{"bind_name_context", cty.True})
+	}
+
+	if len(cf.context) > 0 {
+		attrs = append(attrs, blockAttribute{"encryption_context", stringMapVal(cf.context)})
+	}
+
+	f := hclwrite.NewEmptyFile()
+	appendParameterBlock(f.Body(), resourceLabel, attrs)
+
+	if _, err := stdout.Write(hclwrite.Format(f.Bytes())); err != nil {
+		return commandError(err)
+	}
+
+	return 0
+}
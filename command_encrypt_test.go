@@ -0,0 +1,222 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+// testParseParameterBlock parses the single encryptedssm_parameter block of
+// src and returns its label and argument values.
+func testParseParameterBlock(t *testing.T, src string) (string, map[string]cty.Value) {
+	file, diags := hclsyntax.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("error parsing output: %s\n%s", diags, src)
+	}
+
+	blocks := file.Body.(*hclsyntax.Body).Blocks
+	if len(blocks) != 1 || blocks[0].Type != "resource" || blocks[0].Labels[0] != "encryptedssm_parameter" {
+		t.Fatalf("expected a single encryptedssm_parameter block, got:\n%s", src)
+	}
+
+	attrs := make(map[string]cty.Value)
+	for name, attr := range blocks[0].Body.Attributes {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			t.Fatalf("error evaluating %s: %s", name, diags)
+		}
+
+		attrs[name] = value
+	}
+
+	return blocks[0].Labels[1], attrs
+}
+
+func TestRunEncrypt(t *testing.T) {
+	testCommandKMS(t)
+
+	code, out, errOut := testRunCommand(t, runEncrypt, "MyStr0ngp@ss!\n",
+		"-region", "us-east-1", "-key", "alias/test", "-name", "/app/db/password",
+		"-context", "environment=prod", "-bind-name")
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	label, attrs := testParseParameterBlock(t, out)
+
+	if label != "app_db_password" {
+		t.Errorf("expected resource label app_db_password, got %s", label)
+	}
+
+	for name, expected := range map[string]cty.Value{
+		"name":               cty.StringVal("/app/db/password"),
+		"type":               cty.StringVal("SecureString"),
+		"encryption_key":     cty.StringVal("alias/test"),
+		"bind_name_context":  cty.True,
+		"encryption_context": cty.ObjectVal(map[string]cty.Value{"environment": cty.StringVal("prod")}),
+	} {
+		if v, ok := attrs[name]; !ok || !v.RawEquals(expected) {
+			t.Errorf("expected %s = %#v, got %#v", name, expected, v)
+		}
+	}
+
+	if _, ok := attrs["encryption_algorithm"]; ok {
+		t.Errorf("expected no encryption_algorithm for a symmetric key")
+	}
+
+	// The trailing newline is removed and the name is bound into the context
+	code, plaintext, errOut := testRunCommand(t, runDecrypt, "",
+		"-region", "us-east-1", "-key", "alias/test", "-name", "/app/db/password",
+		"-context", "environment=prod", "-bind-name", attrs["encrypted_value"].AsString())
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	if plaintext != "MyStr0ngp@ss!" {
+		t.Fatalf("expected encrypted_value to decrypt to the input, got %q", plaintext)
+	}
+
+	if code, _, _ := testRunCommand(t, runDecrypt, "", "-region", "us-east-1", "-key", "alias/test",
+		"-context", "environment=prod", attrs["encrypted_value"].AsString()); code == 0 {
+		t.Fatalf("expected decrypting without %s in the context to fail", encryptedssm.ParameterNameContextKey)
+	}
+}
+
+func TestRunEncrypt_keepNewline(t *testing.T) {
+	testCommandKMS(t)
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "value")
+	if err := ioutil.WriteFile(in, []byte("line1\nline2\n"), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	code, out, errOut := testRunCommand(t, runEncrypt, "",
+		"-region", "us-east-1", "-key", "alias/test", "-name", "config", "-type", "String",
+		"-resource", "app_config", "-in", in, "-keep-newline")
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	label, attrs := testParseParameterBlock(t, out)
+
+	if label != "app_config" {
+		t.Errorf("expected resource label app_config, got %s", label)
+	}
+
+	if v := attrs["type"]; !v.RawEquals(cty.StringVal("String")) {
+		t.Errorf("expected type String, got %#v", v)
+	}
+
+	code, plaintext, errOut := testRunCommand(t, runDecrypt, attrs["encrypted_value"].AsString()+"\n",
+		"-region", "us-east-1", "-key", "alias/test")
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	if plaintext != "line1\nline2\n" {
+		t.Fatalf("expected the trailing newline to be kept, got %q", plaintext)
+	}
+}
+
+func TestRunEncrypt_publicKey(t *testing.T) {
+	kms := testCommandKMS(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	publicKey := filepath.Join(t.TempDir(), "public.pem")
+	if err := ioutil.WriteFile(publicKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	args := []string{"-key", "alias/asymmetric", "-name", "password", "-public-key", publicKey}
+
+	if code, _, errOut := testRunCommand(t, runEncrypt, "MyStr0ngp@ss!", args...); code != 1 || !strings.Contains(errOut, "-ssm-key is required") {
+		t.Fatalf("expected -ssm-key to be required, got exit status %d: %s", code, errOut)
+	}
+
+	code, out, errOut := testRunCommand(t, runEncrypt, "MyStr0ngp@ss!", append(args, "-ssm-key", "alias/aws/ssm")...)
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	if kms.requests != 0 {
+		t.Errorf("expected no KMS requests, got %d", kms.requests)
+	}
+
+	_, attrs := testParseParameterBlock(t, out)
+
+	for name, expected := range map[string]string{
+		"encryption_key":       "alias/asymmetric",
+		"encryption_algorithm": "RSAES_OAEP_SHA_256",
+		"ssm_key_id":           "alias/aws/ssm",
+	} {
+		if v, ok := attrs[name]; !ok || v.AsString() != expected {
+			t.Errorf("expected %s = %q, got %#v", name, expected, v)
+		}
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(attrs["encrypted_value"].AsString())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, blob, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(plaintext) != "MyStr0ngp@ss!" {
+		t.Fatalf("expected encrypted_value to decrypt to the input, got %q", plaintext)
+	}
+}
+
+func TestRunEncrypt_invalidArguments(t *testing.T) {
+	testCommandKMS(t)
+
+	testCases := []struct {
+		args     []string
+		code     int
+		expected string
+	}{
+		{[]string{"-name", "password"}, 1, "-key is required"},
+		{[]string{"-key", "alias/test"}, 1, "-name is required"},
+		{[]string{"-key", "alias/test", "-bind-name"}, 1, "-bind-name requires -name"},
+		{[]string{"-key", "alias/test", "-name", "password", "-type", "StringList"}, 1, "-type must be SecureString or String"},
+		{[]string{"-key", "alias/test", "-name", "password", "-context", "environment"}, 2, "expected KEY=VALUE"},
+		{[]string{"-key", "alias/test", "-name", "password", "-algorithm", "RSAES_OAEP_SHA_1"}, 1, "-ssm-key is required"},
+	}
+
+	for _, tc := range testCases {
+		code, out, errOut := testRunCommand(t, runEncrypt, "MyStr0ngp@ss!", tc.args...)
+
+		if code != tc.code || !strings.Contains(errOut, tc.expected) {
+			t.Errorf("%v: expected exit status %d and error %q, got %d: %s", tc.args, tc.code, tc.expected, code, errOut)
+		}
+
+		if out != "" {
+			t.Errorf("%v: expected no output, got:\n%s", tc.args, out)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/zclconf/go-cty/cty"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const migrateUsage = `Usage: terraform-provider-encryptedssm migrate -key KEY [options] [STATE]
+
+  Reads the JSON state of a configuration, as written by terraform show -json,
+  from STATE or standard input and writes an equivalent encryptedssm_parameter
+  resource for every SecureString aws_ssm_parameter, with its value encrypted
+  under -key, followed by import blocks to adopt the existing parameters. As
+  parameters can only be imported with a symmetric KMS key, -key must be one.
+  Parameters stored with an AWS managed KMS key, such as alias/aws/ssm, keep
+  it as ssm_key_id and have value_hash_key_id set to -key.
+
+  The commands to remove the aws_ssm_parameter resources from the state, so
+  Terraform does not destroy the parameters once their configuration is
+  deleted, are written to standard error.
+
+Options:
+`
+
+// migrateState is the subset of the terraform show -json output read by
+// migrate. It is decoded directly rather than with tfjson.State, which
+// rejects format versions newer than it knows, as only the values are used.
+type migrateState struct {
+	FormatVersion string              `json:"format_version"`
+	Values        *tfjson.StateValues `json:"values"`
+}
+
+func runMigrate(args []string) int {
+	var cf cipherFlags
+	var out string
+	var importCommands bool
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), migrateUsage)
+		fs.PrintDefaults()
+	}
+
+	cf.register(fs)
+	fs.StringVar(&out, "out", "", "file to write the configuration to instead of standard output")
+	fs.BoolVar(&importCommands, "import-commands", false, "write terraform import commands to standard error instead of import blocks, for Terraform before 1.5")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Not cf.validate, -bind-name binds the name of each parameter rather than -name
+	if cf.key == "" {
+		return commandError(fmt.Errorf("-key is required"))
+	}
+
+	if cf.name != "" {
+		return commandError(fmt.Errorf("-name is not supported, each parameter keeps its own name"))
+	}
+
+	// The import blocks can only be applied with a symmetric key, the importer
+	// encrypts each value without an encryption algorithm
+	if cf.algorithm != "" && cf.algorithm != kms.EncryptionAlgorithmSpecSymmetricDefault {
+		return commandError(fmt.Errorf("-algorithm %s is not supported, parameters can only be imported with a symmetric KMS key", cf.algorithm))
+	}
+
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	input, err := readInput(fs.Arg(0))
+	if err != nil {
+		return commandError(fmt.Errorf("error reading state: %s", err))
+	}
+
+	var state migrateState
+	if err := json.Unmarshal(input, &state); err != nil {
+		return commandError(fmt.Errorf("error decoding state: %s", err))
+	}
+
+	if state.Values == nil || state.Values.RootModule == nil {
+		return commandError(fmt.Errorf("state has no resources, expected the output of terraform show -json"))
+	}
+
+	opts := cf.options()
+
+	client, err := cf.client()
+	if err != nil {
+		return commandError(err)
+	}
+
+	encrypt := func(name string, plaintext []byte) (string, error) {
+		parameterOpts := opts
+		if cf.bindName {
+			parameterOpts.EncryptionContext = make(map[string]string, len(opts.EncryptionContext))
+			for k, v := range opts.EncryptionContext {
+				parameterOpts.EncryptionContext[k] = v
+			}
+			parameterOpts.EncryptionContext[encryptedssm.ParameterNameContextKey] = name
+		}
+
+		return encryptedssm.EncryptValue(client, parameterOpts, plaintext)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	var imports []string
+	var removals []string
+	labels := make(map[string]bool)
+
+	for _, r := range ssmParameterResources(state.Values.RootModule) {
+		name, _ := r.AttributeValues["name"].(string)
+		value, _ := r.AttributeValues["value"].(string)
+
+		encryptedValue, err := encrypt(name, []byte(value))
+		if err != nil {
+			return commandError(fmt.Errorf("error encrypting %s: %s", r.Address, err))
+		}
+
+		// module.app.aws_ssm_parameter.db[0] becomes module_app_db_0
+		baseLabel := parameterResourceLabel(strings.Replace(r.Address, "aws_ssm_parameter.", "", 1))
+		label := baseLabel
+		for i := 2; labels[label]; i++ {
+			label = fmt.Sprintf("%s_%d", baseLabel, i)
+		}
+		labels[label] = true
+
+		// Value hash keys cannot be generated under AWS managed keys, whose key
+		// policies only allow them to be used through SSM
+		var valueHashKeyId string
+		if keyId, _ := r.AttributeValues["key_id"].(string); awsManagedKmsKey(keyId) {
+			valueHashKeyId = cf.key
+			fmt.Fprintf(stderr, "Warning: %s is stored with the AWS managed KMS key %s, its value hash key is generated under %s instead\n", r.Address, keyId, cf.key)
+		}
+
+		appendParameterBlock(f.Body(), label, migrateParameterAttributes(r, &cf, opts.EncryptionAlgorithm, encryptedValue, valueHashKeyId))
+		f.Body().AppendNewline()
+
+		// The import ID carries the arguments the value is encrypted with, so
+		// the imported parameter plans clean
+		address := "encryptedssm_parameter." + label
+		importId, err := encryptedssm.ParameterImportId(name, cf.key, encryptedssm.ParameterImportArguments{
+			BindNameContext:   cf.bindName,
+			EncryptionContext: cf.context,
+			ValueHashKeyId:    valueHashKeyId,
+		})
+		if err != nil {
+			return commandError(fmt.Errorf("error writing import ID of %s: %s", r.Address, err))
+		}
+
+		if importCommands {
+			imports = append(imports, fmt.Sprintf("terraform import %s %s", shellQuote(address), shellQuote(importId)))
+		} else {
+			importBody := f.Body().AppendNewBlock("import", nil).Body()
+			importBody.SetAttributeTraversal("to", hcl.Traversal{
+				hcl.TraverseRoot{Name: "encryptedssm_parameter"},
+				hcl.TraverseAttr{Name: label},
+			})
+			importBody.SetAttributeValue("id", cty.StringVal(importId))
+			f.Body().AppendNewline()
+		}
+
+		removals = append(removals, fmt.Sprintf("terraform state rm %s", shellQuote(r.Address)))
+	}
+
+	if len(removals) == 0 {
+		fmt.Fprintln(stderr, "No SecureString aws_ssm_parameter resources found in the state")
+		return 0
+	}
+
+	src := hclwrite.Format(f.Bytes())
+	if out == "" {
+		_, err = stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(out, src, 0644)
+	}
+	if err != nil {
+		return commandError(err)
+	}
+
+	if importCommands {
+		fmt.Fprintf(stderr, "\nImport the parameters into the new resources:\n\n  %s\n", strings.Join(imports, "\n  "))
+	}
+
+	fmt.Fprintf(stderr, "\nThen delete the aws_ssm_parameter resources from the configuration and remove them from the state\nso the parameters are not destroyed:\n\n  %s\n", strings.Join(removals, "\n  "))
+
+	return 0
+}
+
+// ssmParameterResources returns the SecureString aws_ssm_parameter resources
+// of a module and its child modules, ordered by address.
+func ssmParameterResources(module *tfjson.StateModule) []*tfjson.StateResource {
+	var resources []*tfjson.StateResource
+
+	for _, r := range module.Resources {
+		if r.Mode != tfjson.ManagedResourceMode || r.Type != "aws_ssm_parameter" {
+			continue
+		}
+
+		if t, _ := r.AttributeValues["type"].(string); t != ssm.ParameterTypeSecureString {
+			continue
+		}
+
+		resources = append(resources, r)
+	}
+
+	for _, child := range module.ChildModules {
+		resources = append(resources, ssmParameterResources(child)...)
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+
+	return resources
+}
+
+// awsManagedKmsKey returns whether a KMS key ID is the alias, or alias ARN, of
+// an AWS managed key such as alias/aws/ssm.
+func awsManagedKmsKey(keyId string) bool {
+	return strings.HasPrefix(keyId, "alias/aws/") || strings.Contains(keyId, ":alias/aws/")
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// migrateParameterAttributes returns the arguments of the
+// encryptedssm_parameter resource equivalent to an aws_ssm_parameter.
+func migrateParameterAttributes(r *tfjson.StateResource, cf *cipherFlags, algorithm, encryptedValue, valueHashKeyId string) []blockAttribute {
+	values := r.AttributeValues
+	name, _ := values["name"].(string)
+
+	attrs := []blockAttribute{
+		{"name", cty.StringVal(name)},
+		{"type", cty.StringVal(ssm.ParameterTypeSecureString)},
+	}
+
+	if v, _ := values["description"].(string); v != "" {
+		attrs = append(attrs, blockAttribute{"description", cty.StringVal(v)})
+	}
+
+	if v, _ := values["tier"].(string); v != "" && v != ssm.ParameterTierStandard {
+		attrs = append(attrs, blockAttribute{"tier", cty.StringVal(v)})
+	}
+
+	attrs = append(attrs, blockAttribute{"encryption_key", cty.StringVal(cf.key)})
+
+	if algorithm != "" {
+		attrs = append(attrs, blockAttribute{"encryption_algorithm", cty.StringVal(algorithm)})
+	}
+
+	// Keep the parameter stored with the same key in SSM
+	if v, _ := values["key_id"].(string); v != "" && v != cf.key {
+		attrs = append(attrs, blockAttribute{"ssm_key_id", cty.StringVal(v)})
+	}
+
+	if valueHashKeyId != "" {
+		attrs = append(attrs, blockAttribute{"value_hash_key_id", cty.StringVal(valueHashKeyId)})
+	}
+
+	attrs = append(attrs, blockAttribute{"encrypted_value", cty.StringVal(encryptedValue)})
+
+	if cf.bindName {
+		attrs = append(attrs, blockAttribute{"bind_name_context", cty.True})
+	}
+
+	if v, _ := values["allowed_pattern"].(string); v != "" {
+		attrs = append(attrs, blockAttribute{"allowed_pattern", cty.StringVal(v)})
+	}
+
+	if v, _ := values["data_type"].(string); v != "" && v != "text" {
+		attrs = append(attrs, blockAttribute{"data_type", cty.StringVal(v)})
+	}
+
+	if len(cf.context) > 0 {
+		attrs = append(attrs, blockAttribute{"encryption_context", stringMapVal(cf.context)})
+	}
+
+	if v, _ := values["tags"].(map[string]interface{}); len(v) > 0 {
+		tags := make(map[string]string, len(v))
+		for k, tv := range v {
+			tags[k], _ = tv.(string)
+		}
+
+		attrs = append(attrs, blockAttribute{"tags", stringMapVal(tags)})
+	}
+
+	return attrs
+}
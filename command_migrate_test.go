@@ -0,0 +1,356 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const testMigrateState = `{
+  "format_version": "1.0",
+  "terraform_version": "1.5.7",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "aws_ssm_parameter.db_password",
+          "mode": "managed",
+          "type": "aws_ssm_parameter",
+          "name": "db_password",
+          "provider_name": "registry.terraform.io/hashicorp/aws",
+          "values": {
+            "name": "/app/db/password",
+            "type": "SecureString",
+            "value": "MyStr0ngp@ss!",
+            "description": "Database password",
+            "tier": "Advanced",
+            "key_id": "alias/aws/ssm",
+            "data_type": "text",
+            "tags": {"team": "platform"}
+          }
+        },
+        {
+          "address": "aws_ssm_parameter.endpoint",
+          "mode": "managed",
+          "type": "aws_ssm_parameter",
+          "name": "endpoint",
+          "values": {"name": "/app/endpoint", "type": "String", "value": "https://example.com"}
+        },
+        {
+          "address": "data.aws_ssm_parameter.api_key",
+          "mode": "data",
+          "type": "aws_ssm_parameter",
+          "name": "api_key",
+          "values": {"name": "/app/api-key", "type": "SecureString", "value": "secret"}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.cache",
+          "resources": [
+            {
+              "address": "module.cache.aws_ssm_parameter.auth_token[0]",
+              "mode": "managed",
+              "type": "aws_ssm_parameter",
+              "name": "auth_token",
+              "index": 0,
+              "values": {"name": "/cache/auth-token", "type": "SecureString", "value": "t0ken", "tier": "Standard", "key_id": "alias/cache"}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}
+`
+
+// testParseBlocks parses src and returns its top level blocks.
+func testParseBlocks(t *testing.T, src string) hclsyntax.Blocks {
+	file, diags := hclsyntax.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("error parsing output: %s\n%s", diags, src)
+	}
+
+	return file.Body.(*hclsyntax.Body).Blocks
+}
+
+// testBlockValues returns the values of the literal arguments of a block.
+func testBlockValues(t *testing.T, block *hclsyntax.Block) map[string]cty.Value {
+	values := make(map[string]cty.Value)
+	for name, attr := range block.Body.Attributes {
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			continue
+		}
+
+		values[name] = v
+	}
+
+	return values
+}
+
+// testImportTarget returns the address an import block imports to.
+func testImportTarget(t *testing.T, block *hclsyntax.Block) string {
+	traversal, diags := hcl.AbsTraversalForExpr(block.Body.Attributes["to"].Expr)
+	if diags.HasErrors() {
+		t.Fatalf("error reading import block to: %s", diags)
+	}
+
+	return traversal.RootName() + "." + traversal[1].(hcl.TraverseAttr).Name
+}
+
+func TestRunMigrate(t *testing.T) {
+	testCommandKMS(t)
+
+	code, out, errOut := testRunCommand(t, runMigrate, testMigrateState,
+		"-region", "us-east-1", "-key", "alias/test", "-context", "environment=prod", "-bind-name")
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	blocks := testParseBlocks(t, out)
+	if len(blocks) != 4 {
+		t.Fatalf("expected a resource and an import block for each SecureString parameter, got:\n%s", out)
+	}
+
+	testCases := []struct {
+		label    string
+		name     string
+		value    string
+		importId string
+		expected map[string]cty.Value
+	}{
+		{
+			"db_password",
+			"/app/db/password",
+			"MyStr0ngp@ss!",
+			`/app/db/password,alias/test,{"bind_name_context":true,"encryption_context":{"environment":"prod"},"value_hash_key_id":"alias/test"}`,
+			map[string]cty.Value{
+				"type":              cty.StringVal("SecureString"),
+				"description":       cty.StringVal("Database password"),
+				"tier":              cty.StringVal("Advanced"),
+				"encryption_key":    cty.StringVal("alias/test"),
+				"ssm_key_id":        cty.StringVal("alias/aws/ssm"),
+				"value_hash_key_id": cty.StringVal("alias/test"),
+				"bind_name_context": cty.True,
+				"encryption_context": cty.ObjectVal(map[string]cty.Value{
+					"environment": cty.StringVal("prod"),
+				}),
+				"tags": cty.ObjectVal(map[string]cty.Value{
+					"team": cty.StringVal("platform"),
+				}),
+			},
+		},
+		{
+			"module_cache_auth_token_0",
+			"/cache/auth-token",
+			"t0ken",
+			`/cache/auth-token,alias/test,{"bind_name_context":true,"encryption_context":{"environment":"prod"}}`,
+			map[string]cty.Value{
+				"encryption_key":    cty.StringVal("alias/test"),
+				"ssm_key_id":        cty.StringVal("alias/cache"),
+				"bind_name_context": cty.True,
+			},
+		},
+	}
+
+	for i, tc := range testCases {
+		resource, importBlock := blocks[2*i], blocks[2*i+1]
+
+		if resource.Type != "resource" || resource.Labels[0] != "encryptedssm_parameter" || resource.Labels[1] != tc.label {
+			t.Errorf("expected resource encryptedssm_parameter.%s, got %s %v", tc.label, resource.Type, resource.Labels)
+			continue
+		}
+
+		values := testBlockValues(t, resource)
+
+		if v := values["name"]; !v.RawEquals(cty.StringVal(tc.name)) {
+			t.Errorf("%s: expected name %q, got %#v", tc.label, tc.name, v)
+		}
+
+		for name, expected := range tc.expected {
+			if v, ok := values[name]; !ok || !v.RawEquals(expected) {
+				t.Errorf("%s: expected %s = %#v, got %#v", tc.label, name, expected, v)
+			}
+		}
+
+		for _, name := range []string{"data_type", "encryption_algorithm", "value_hash_key_id"} {
+			if _, ok := tc.expected[name]; ok {
+				continue
+			}
+
+			if _, ok := values[name]; ok {
+				t.Errorf("%s: expected no %s", tc.label, name)
+			}
+		}
+
+		context := map[string]string{
+			"environment":                        "prod",
+			encryptedssm.ParameterNameContextKey: tc.name,
+		}
+
+		if v := testCommandDecrypt(t, encryptedssm.CipherOptions{KeyId: "alias/test", EncryptionContext: context}, values["encrypted_value"].AsString()); v != tc.value {
+			t.Errorf("%s: expected encrypted_value to decrypt to the state value, got %q", tc.label, v)
+		}
+
+		if importBlock.Type != "import" {
+			t.Errorf("%s: expected an import block, got %s", tc.label, importBlock.Type)
+			continue
+		}
+
+		if to := testImportTarget(t, importBlock); to != "encryptedssm_parameter."+tc.label {
+			t.Errorf("%s: expected import to encryptedssm_parameter.%s, got %s", tc.label, tc.label, to)
+		}
+
+		// The import ID carries the encryption context so the imported
+		// parameter matches the configuration
+		if id := testBlockValues(t, importBlock)["id"]; !id.RawEquals(cty.StringVal(tc.importId)) {
+			t.Errorf("%s: expected import ID %s, got %#v", tc.label, tc.importId, id)
+		}
+	}
+
+	if expected := "Warning: aws_ssm_parameter.db_password is stored with the AWS managed KMS key alias/aws/ssm"; !strings.Contains(errOut, expected) {
+		t.Errorf("expected standard error to contain %q, got:\n%s", expected, errOut)
+	}
+
+	if strings.Contains(errOut, "auth_token[0] is stored with the AWS managed KMS key") {
+		t.Errorf("expected no warning for a customer managed key, got:\n%s", errOut)
+	}
+
+	for _, expected := range []string{
+		"terraform state rm 'aws_ssm_parameter.db_password'",
+		"terraform state rm 'module.cache.aws_ssm_parameter.auth_token[0]'",
+	} {
+		if !strings.Contains(errOut, expected) {
+			t.Errorf("expected standard error to contain %q, got:\n%s", expected, errOut)
+		}
+	}
+
+	if strings.Contains(errOut, "terraform import") {
+		t.Errorf("expected no import commands, got:\n%s", errOut)
+	}
+}
+
+func TestRunMigrate_importCommands(t *testing.T) {
+	testCommandKMS(t)
+
+	dir := t.TempDir()
+	state := filepath.Join(dir, "state.json")
+	out := filepath.Join(dir, "parameters.tf")
+
+	if err := ioutil.WriteFile(state, []byte(testMigrateState), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	code, stdoutOut, errOut := testRunCommand(t, runMigrate, "",
+		"-region", "us-east-1", "-key", "alias/test", "-import-commands", "-out", out, state)
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	if stdoutOut != "" {
+		t.Errorf("expected no output with -out, got:\n%s", stdoutOut)
+	}
+
+	src, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	blocks := testParseBlocks(t, string(src))
+	if len(blocks) != 2 || blocks[0].Type != "resource" || blocks[1].Type != "resource" {
+		t.Fatalf("expected only resource blocks, got:\n%s", src)
+	}
+
+	if v := testBlockValues(t, blocks[0])["encrypted_value"]; testCommandDecrypt(t, encryptedssm.CipherOptions{KeyId: "alias/test"}, v.AsString()) != "MyStr0ngp@ss!" {
+		t.Errorf("expected encrypted_value to decrypt to the state value")
+	}
+
+	for _, expected := range []string{
+		`terraform import 'encryptedssm_parameter.db_password' '/app/db/password,alias/test,{"value_hash_key_id":"alias/test"}'`,
+		"terraform import 'encryptedssm_parameter.module_cache_auth_token_0' '/cache/auth-token,alias/test'",
+		"terraform state rm 'aws_ssm_parameter.db_password'",
+	} {
+		if !strings.Contains(errOut, expected) {
+			t.Errorf("expected standard error to contain %q, got:\n%s", expected, errOut)
+		}
+	}
+}
+
+func TestAwsManagedKmsKey(t *testing.T) {
+	for keyId, expected := range map[string]bool{
+		"alias/aws/ssm": true,
+		"arn:aws:kms:us-east-1:123456789012:alias/aws/ssm": true,
+		"alias/app":    false,
+		"alias/awsome": false,
+		"arn:aws:kms:us-east-1:123456789012:alias/app":                                false,
+		"arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab": false,
+		"": false,
+	} {
+		if v := awsManagedKmsKey(keyId); v != expected {
+			t.Errorf("expected awsManagedKmsKey(%q) to be %t", keyId, expected)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if v := shellQuote(`/app,alias/test,{"encryption_context":{"owner":"o'brien"}}`); v != `'/app,alias/test,{"encryption_context":{"owner":"o'\''brien"}}'` {
+		t.Errorf("unexpected quoting: %s", v)
+	}
+}
+
+func TestRunMigrate_noParameters(t *testing.T) {
+	kms := testCommandKMS(t)
+
+	code, out, errOut := testRunCommand(t, runMigrate, `{"format_version": "1.0", "values": {"root_module": {}}}`,
+		"-region", "us-east-1", "-key", "alias/test")
+	if code != 0 || out != "" || !strings.Contains(errOut, "No SecureString aws_ssm_parameter resources found") {
+		t.Fatalf("expected no parameters to be found, got exit status %d:\n%s%s", code, out, errOut)
+	}
+
+	if kms.requests != 0 {
+		t.Errorf("expected no KMS requests, got %d", kms.requests)
+	}
+}
+
+func TestRunMigrate_invalidArguments(t *testing.T) {
+	kms := testCommandKMS(t)
+
+	testCases := []struct {
+		input    string
+		args     []string
+		code     int
+		expected string
+	}{
+		{testMigrateState, []string{}, 1, "-key is required"},
+		{testMigrateState, []string{"-key", "alias/test", "-name", "/app/db/password"}, 1, "-name is not supported"},
+		{testMigrateState, []string{"-key", "alias/test", "-algorithm", "RSAES_OAEP_SHA_256"}, 1, "parameters can only be imported with a symmetric KMS key"},
+		{testMigrateState, []string{"-key", "alias/test", "-public-key", "public.pem"}, 2, "flag provided but not defined: -public-key"},
+		{testMigrateState, []string{"-key", "alias/test", "a.json", "b.json"}, 2, "Usage:"},
+		{"{", []string{"-key", "alias/test"}, 1, "error decoding state"},
+		{`{"format_version": "1.0"}`, []string{"-key", "alias/test"}, 1, "state has no resources"},
+	}
+
+	for _, tc := range testCases {
+		code, out, errOut := testRunCommand(t, runMigrate, tc.input, append([]string{"-region", "us-east-1"}, tc.args...)...)
+
+		if code != tc.code || !strings.Contains(errOut, tc.expected) {
+			t.Errorf("%v: expected exit status %d and error %q, got %d: %s", tc.args, tc.code, tc.expected, code, errOut)
+		}
+
+		if out != "" {
+			t.Errorf("%v: expected no output, got:\n%s", tc.args, out)
+		}
+	}
+
+	if kms.requests != 0 {
+		t.Errorf("expected no KMS requests, got %d", kms.requests)
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const rotateUsage = `Usage: terraform-provider-encryptedssm rotate -new-key KEY [options] [PATH...]
+
+  Re-encrypts the encrypted_value, encrypted_list, encrypted_fields or
+  encrypted_values of every encryptedssm_parameter resource in the given .tf
+  files, or the .tf files of the given directories, under a new KMS key and
+  rewrites the files in place.
+  PATH defaults to the current directory.
+
+  Each value is decrypted with the encryption_key, encryption_algorithm and
+  encryption context of its resource and encrypted under -new-key with the same
+  encryption context. Resources without an ssm_key_id get one set to the old
+  encryption_key, so the key SSM stores the parameter with does not change.
+  Resources whose arguments are not literal values are reported and left
+  unchanged.
+
+Options:
+`
+
+func runRotate(args []string) int {
+	var cf cipherFlags
+	var oldKey, newKey, newAlgorithm string
+	var dryRun bool
+
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), rotateUsage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cf.region, "region", defaultRegion(), "AWS region, defaults to AWS_REGION or AWS_DEFAULT_REGION")
+	fs.StringVar(&cf.profile, "profile", "", "AWS shared credentials profile")
+	fs.StringVar(&oldKey, "old-key", "", "only rotate resources whose encryption_key is this value")
+	fs.StringVar(&newKey, "new-key", "", "KMS key ID, ARN or alias to re-encrypt with")
+	fs.StringVar(&newAlgorithm, "new-algorithm", "", "encryption algorithm for an asymmetric -new-key")
+	fs.BoolVar(&dryRun, "dry-run", false, "check every value can be decrypted and report the changes without encrypting or writing")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if newKey == "" {
+		return commandError(fmt.Errorf("-new-key is required"))
+	}
+
+	paths, err := terraformFiles(fs.Args())
+	if err != nil {
+		return commandError(err)
+	}
+
+	client, err := cf.client()
+	if err != nil {
+		return commandError(err)
+	}
+
+	r := &rotator{
+		client:       client,
+		oldKey:       oldKey,
+		newKey:       newKey,
+		newAlgorithm: newAlgorithm,
+		dryRun:       dryRun,
+	}
+
+	status := 0
+	for _, path := range paths {
+		if err := r.rotateFile(path); err != nil {
+			fmt.Fprintf(stderr, "Error: %s: %s\n", path, err)
+			status = 1
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(stdout, "%d resource(s) to re-encrypt, %d skipped\n", r.rotated, r.skipped)
+	} else {
+		fmt.Fprintf(stdout, "%d resource(s) re-encrypted, %d skipped\n", r.rotated, r.skipped)
+	}
+
+	return status
+}
+
+// terraformFiles returns the given files and the .tf files of the given
+// directories, the current directory when none are given.
+func terraformFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, matches...)
+	}
+
+	return paths, nil
+}
+
+// rotator re-encrypts the encryptedssm_parameter resources of files.
+type rotator struct {
+	client       interface{}
+	oldKey       string
+	newKey       string
+	newAlgorithm string
+	dryRun       bool
+
+	rotated int
+	skipped int
+}
+
+// rotateFile re-encrypts the resources in a file and rewrites it when any
+// changed. The file is parsed twice: hclsyntax evaluates the literal
+// arguments and hclwrite edits the file preserving its formatting. Top level
+// blocks are in declaration order in both.
+func (r *rotator) rotateFile(path string) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	syntaxFile, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return diags
+	}
+
+	writeFile, diags := hclwrite.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return diags
+	}
+
+	syntaxBlocks := syntaxFile.Body.(*hclsyntax.Body).Blocks
+	writeBlocks := writeFile.Body().Blocks()
+	if len(syntaxBlocks) != len(writeBlocks) {
+		return fmt.Errorf("unexpected number of blocks")
+	}
+
+	changed := false
+	for i, block := range syntaxBlocks {
+		if block.Type != "resource" || len(block.Labels) != 2 || block.Labels[0] != "encryptedssm_parameter" {
+			continue
+		}
+
+		address := fmt.Sprintf("%s:%d: encryptedssm_parameter.%s", path, block.TypeRange.Start.Line, block.Labels[1])
+
+		ok, err := r.rotateBlock(block.Body, writeBlocks[i].Body())
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: skipped, %s\n", address, err)
+			r.skipped++
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if r.dryRun {
+			fmt.Fprintf(stdout, "%s: would re-encrypt under %s\n", address, r.newKey)
+		} else {
+			fmt.Fprintf(stdout, "%s: re-encrypted under %s\n", address, r.newKey)
+		}
+
+		r.rotated++
+		changed = true
+	}
+
+	if !changed || r.dryRun {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, writeFile.Bytes(), info.Mode())
+}
+
+// rotateBlock re-encrypts the value of a single resource, returning false if
+// the resource is not encrypted with KMS, is not encrypted with -old-key or
+// already uses -new-key.
+func (r *rotator) rotateBlock(body *hclsyntax.Body, writeBody *hclwrite.Body) (bool, error) {
+	scheme, err := literalString(body, "encryption_scheme")
+	if err != nil {
+		return false, err
+	}
+
+	// Only KMS values are encrypted with a KMS encryption_key, the other
+	// schemes decrypt with the provider configuration or a Vault Transit key
+	if scheme != "" && scheme != "kms" {
+		return false, nil
+	}
+
+	key, err := literalString(body, "encryption_key")
+	if err != nil {
+		return false, err
+	}
+
+	algorithm, err := literalString(body, "encryption_algorithm")
+	if err != nil {
+		return false, err
+	}
+
+	if (r.oldKey != "" && key != r.oldKey) || (key == r.newKey && algorithm == r.newAlgorithm) {
+		return false, nil
+	}
+
+	bindName, err := literalValue(body, "bind_name_context", cty.Bool)
+	if err != nil {
+		return false, err
+	}
+
+	encryptionContext, err := literalValue(body, "encryption_context", cty.Map(cty.String))
+	if err != nil {
+		return false, err
+	}
+
+	context := make(map[string]string)
+	if !encryptionContext.IsNull() {
+		for k, v := range encryptionContext.AsValueMap() {
+			if v.IsNull() {
+				return false, fmt.Errorf("encryption_context %q is null", k)
+			}
+
+			context[k] = v.AsString()
+		}
+	}
+
+	// The name is only needed, and so only required to be a literal, when it
+	// is bound into the encryption context
+	if !bindName.IsNull() && bindName.True() {
+		name, err := literalString(body, "name")
+		if err != nil {
+			return false, err
+		}
+
+		context[encryptedssm.ParameterNameContextKey] = name
+	}
+
+	oldOpts := encryptedssm.CipherOptions{
+		KeyId:               key,
+		EncryptionAlgorithm: algorithm,
+		EncryptionContext:   context,
+	}
+
+	newOpts := encryptedssm.CipherOptions{
+		KeyId:               r.newKey,
+		EncryptionAlgorithm: r.newAlgorithm,
+		EncryptionContext:   context,
+	}
+
+	value, err := literalValue(body, "encrypted_value", cty.String)
+	if err != nil {
+		return false, err
+	}
+
+	list, err := literalValue(body, "encrypted_list", cty.List(cty.String))
+	if err != nil {
+		return false, err
+	}
+
+	fields, err := literalValue(body, "encrypted_fields", cty.Map(cty.String))
+	if err != nil {
+		return false, err
+	}
+
+	values, err := literalValue(body, "encrypted_values", cty.Map(cty.String))
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !value.IsNull():
+		ciphertext, err := r.reencrypt(oldOpts, newOpts, value.AsString())
+		if err != nil {
+			return false, err
+		}
+
+		writeBody.SetAttributeValue("encrypted_value", cty.StringVal(ciphertext))
+	case !list.IsNull() && list.LengthInt() > 0:
+		items := make([]cty.Value, 0, list.LengthInt())
+		for i, v := range list.AsValueSlice() {
+			if v.IsNull() {
+				return false, fmt.Errorf("encrypted_list element %d is null", i)
+			}
+
+			ciphertext, err := r.reencrypt(oldOpts, newOpts, v.AsString())
+			if err != nil {
+				return false, err
+			}
+
+			items = append(items, cty.StringVal(ciphertext))
+		}
+
+		writeBody.SetAttributeValue("encrypted_list", cty.ListVal(items))
+	case !fields.IsNull() && fields.LengthInt() > 0:
+		items, err := r.reencryptMap(oldOpts, newOpts, "encrypted_fields", fields)
+		if err != nil {
+			return false, err
+		}
+
+		writeBody.SetAttributeValue("encrypted_fields", items)
+	case !values.IsNull() && values.LengthInt() > 0:
+		items, err := r.reencryptMap(oldOpts, newOpts, "encrypted_values", values)
+		if err != nil {
+			return false, err
+		}
+
+		writeBody.SetAttributeValue("encrypted_values", items)
+	default:
+		return false, fmt.Errorf("no encrypted_value, encrypted_list, encrypted_fields or encrypted_values")
+	}
+
+	// Without ssm_key_id SSM stores the parameter with encryption_key. Pin it
+	// to the old key so only the ciphertexts move to -new-key, which may be
+	// an asymmetric key SSM cannot store the parameter with.
+	if _, ok := body.Attributes["ssm_key_id"]; !ok && key != r.newKey {
+		writeBody.SetAttributeValue("ssm_key_id", cty.StringVal(key))
+	}
+
+	writeBody.SetAttributeValue("encryption_key", cty.StringVal(r.newKey))
+
+	switch {
+	case r.newAlgorithm != "":
+		writeBody.SetAttributeValue("encryption_algorithm", cty.StringVal(r.newAlgorithm))
+	case algorithm != "":
+		writeBody.SetAttributeValue("encryption_algorithm", cty.StringVal("SYMMETRIC_DEFAULT"))
+	}
+
+	return true, nil
+}
+
+// reencrypt decrypts a ciphertext with oldOpts and encrypts the plaintext
+// with newOpts. On a dry run the ciphertext is only decrypted and returned
+// unchanged.
+func (r *rotator) reencrypt(oldOpts, newOpts encryptedssm.CipherOptions, ciphertext string) (string, error) {
+	plaintext, err := encryptedssm.DecryptValue(r.client, oldOpts, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if r.dryRun {
+		return ciphertext, nil
+	}
+
+	return encryptedssm.EncryptValue(r.client, newOpts, plaintext)
+}
+
+// reencryptMap re-encrypts each value of the map of ciphertexts of argument
+// name.
+func (r *rotator) reencryptMap(oldOpts, newOpts encryptedssm.CipherOptions, name string, m cty.Value) (cty.Value, error) {
+	items := make(map[string]cty.Value, m.LengthInt())
+	for k, v := range m.AsValueMap() {
+		if v.IsNull() {
+			return cty.NilVal, fmt.Errorf("%s %q is null", name, k)
+		}
+
+		ciphertext, err := r.reencrypt(oldOpts, newOpts, v.AsString())
+		if err != nil {
+			return cty.NilVal, err
+		}
+
+		items[k] = cty.StringVal(ciphertext)
+	}
+
+	return cty.MapVal(items), nil
+}
+
+// literalValue returns the value of an attribute converted to ty, a null
+// value if it is not set, or an error if it is not a literal.
+func literalValue(body *hclsyntax.Body, name string, ty cty.Type) (cty.Value, error) {
+	attr, ok := body.Attributes[name]
+	if !ok {
+		return cty.NullVal(ty), nil
+	}
+
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !v.IsWhollyKnown() {
+		return cty.NilVal, fmt.Errorf("%s is not a literal value", name)
+	}
+
+	v, err := convert.Convert(v, ty)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("%s: %s", name, err)
+	}
+
+	return v, nil
+}
+
+// literalString returns the value of a string attribute, or an empty string
+// if it is not set.
+func literalString(body *hclsyntax.Body, name string) (string, error) {
+	v, err := literalValue(body, name, cty.String)
+	if err != nil || v.IsNull() {
+		return "", err
+	}
+
+	return v.AsString(), nil
+}
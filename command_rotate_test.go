@@ -0,0 +1,373 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"terraform-provider-encryptedssm/encryptedssm"
+)
+
+const testRotateConfig = `# Application secrets
+resource "encryptedssm_parameter" "db_password" {
+  name            = "/app/db/password" # the database password
+  type            = "SecureString"
+  encryption_key  = "alias/old"
+  encrypted_value = "PASSWORD"
+
+  bind_name_context = true
+  encryption_context = {
+    environment = "prod"
+  }
+}
+
+resource "aws_s3_bucket" "assets" {
+  bucket = "assets"
+}
+
+// The name is not bound into the context, so it need not be a literal
+resource "encryptedssm_parameter" "hosts" {
+  name           = "${var.prefix}/hosts"
+  type           = "StringList"
+  encryption_key = "alias/old"
+  ssm_key_id     = "alias/storage"
+  encrypted_list = ["HOST1", "HOST2"]
+}
+`
+
+// testWriteRotateConfig writes testRotateConfig with its placeholders replaced
+// by ciphertexts under alias/old and returns the path and content.
+func testWriteRotateConfig(t *testing.T) (string, string) {
+	context := map[string]string{
+		"environment":                        "prod",
+		encryptedssm.ParameterNameContextKey: "/app/db/password",
+	}
+
+	src := strings.NewReplacer(
+		"PASSWORD", testCommandEncrypt(t, encryptedssm.CipherOptions{KeyId: "alias/old", EncryptionContext: context}, "MyStr0ngp@ss!"),
+		"HOST1", testCommandEncrypt(t, encryptedssm.CipherOptions{KeyId: "alias/old"}, "db1.internal"),
+		"HOST2", testCommandEncrypt(t, encryptedssm.CipherOptions{KeyId: "alias/old"}, "db2.internal"),
+	).Replace(testRotateConfig)
+
+	path := filepath.Join(t.TempDir(), "main.tf")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return path, src
+}
+
+// testLiteralStrings returns the string values of an attribute of the
+// encryptedssm_parameter resources in src, in order, as a single string for
+// attributes or one per element for lists.
+func testLiteralStrings(t *testing.T, src, name string) []string {
+	file, diags := hclsyntax.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("error parsing %s", diags)
+	}
+
+	var values []string
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		attr, ok := block.Body.Attributes[name]
+		if !ok || block.Labels[0] != "encryptedssm_parameter" {
+			continue
+		}
+
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			t.Fatalf("error evaluating %s: %s", name, diags)
+		}
+
+		if !v.Type().IsTupleType() && !v.Type().IsListType() {
+			values = append(values, v.AsString())
+			continue
+		}
+
+		for _, item := range v.AsValueSlice() {
+			values = append(values, item.AsString())
+		}
+	}
+
+	return values
+}
+
+func TestRunRotate(t *testing.T) {
+	testCommandKMS(t)
+
+	path, src := testWriteRotateConfig(t)
+
+	code, out, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-old-key", "alias/old", "-new-key", "alias/new", path)
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	for _, expected := range []string{
+		path + ":2: encryptedssm_parameter.db_password: re-encrypted under alias/new",
+		path + ":19: encryptedssm_parameter.hosts: re-encrypted under alias/new",
+		"2 resource(s) re-encrypted, 0 skipped",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+
+	rotated, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	value := testLiteralStrings(t, string(rotated), "encrypted_value")
+	list := testLiteralStrings(t, string(rotated), "encrypted_list")
+	if len(value) != 1 || len(list) != 2 {
+		t.Fatalf("expected an encrypted_value and two encrypted_list elements, got:\n%s", rotated)
+	}
+
+	// Only the keys and ciphertexts change, comments and layout are kept. The
+	// storage key of the resource without an ssm_key_id is pinned to the old
+	// key, the other keeps its own.
+	oldValue := testLiteralStrings(t, src, "encrypted_value")
+	oldList := testLiteralStrings(t, src, "encrypted_list")
+	expected := strings.NewReplacer(
+		`"alias/old"`, `"alias/new"`,
+		"    environment = \"prod\"\n  }\n}", "    environment = \"prod\"\n  }\n  ssm_key_id = \"alias/old\"\n}",
+		oldValue[0], value[0],
+		oldList[0], list[0],
+		oldList[1], list[1],
+	).Replace(src)
+
+	if string(rotated) != expected {
+		t.Fatalf("expected rotated file:\n%s\ngot:\n%s", expected, rotated)
+	}
+
+	context := map[string]string{
+		"environment":                        "prod",
+		encryptedssm.ParameterNameContextKey: "/app/db/password",
+	}
+
+	if v := testCommandDecrypt(t, encryptedssm.CipherOptions{KeyId: "alias/new", EncryptionContext: context}, value[0]); v != "MyStr0ngp@ss!" {
+		t.Errorf("expected encrypted_value to decrypt to the original value, got %q", v)
+	}
+
+	for i, host := range []string{"db1.internal", "db2.internal"} {
+		if v := testCommandDecrypt(t, encryptedssm.CipherOptions{KeyId: "alias/new"}, list[i]); v != host {
+			t.Errorf("expected encrypted_list element %d to decrypt to %q, got %q", i, host, v)
+		}
+	}
+
+	// Resources already under the new key are left alone
+	code, out, errOut = testRunCommand(t, runRotate, "", "-region", "us-east-1", "-new-key", "alias/new", filepath.Dir(path))
+	if code != 0 || !strings.Contains(out, "0 resource(s) re-encrypted, 0 skipped") {
+		t.Fatalf("expected nothing to re-encrypt, got exit status %d:\n%s%s", code, out, errOut)
+	}
+}
+
+func TestRunRotate_asymmetricNewKey(t *testing.T) {
+	testCommandKMS(t)
+
+	src := `resource "encryptedssm_parameter" "test" {
+  name            = "/app/db/password"
+  type            = "SecureString"
+  encryption_key  = "alias/old"
+  encrypted_value = "` + testCommandEncrypt(t, encryptedssm.CipherOptions{KeyId: "alias/old"}, "MyStr0ngp@ss!") + `"
+}
+`
+
+	path := filepath.Join(t.TempDir(), "main.tf")
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	code, out, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-new-key", "alias/rsa", "-new-algorithm", "RSAES_OAEP_SHA_256", path)
+	if code != 0 || !strings.Contains(out, "1 resource(s) re-encrypted, 0 skipped") {
+		t.Fatalf("expected the resource to be re-encrypted, got exit status %d:\n%s%s", code, out, errOut)
+	}
+
+	rotated, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// SSM cannot store the parameter with the asymmetric key, so it stays
+	// stored with the old one
+	for name, expected := range map[string]string{
+		"encryption_key":       "alias/rsa",
+		"encryption_algorithm": "RSAES_OAEP_SHA_256",
+		"ssm_key_id":           "alias/old",
+	} {
+		if v := testLiteralStrings(t, string(rotated), name); len(v) != 1 || v[0] != expected {
+			t.Errorf("expected %s %q, got %q in:\n%s", name, expected, v, rotated)
+		}
+	}
+
+	value := testLiteralStrings(t, string(rotated), "encrypted_value")
+	if v := testCommandDecrypt(t, encryptedssm.CipherOptions{KeyId: "alias/rsa", EncryptionAlgorithm: "RSAES_OAEP_SHA_256"}, value[0]); v != "MyStr0ngp@ss!" {
+		t.Errorf("expected encrypted_value to decrypt to the original value, got %q", v)
+	}
+}
+
+func TestRunRotate_dryRun(t *testing.T) {
+	kms := testCommandKMS(t)
+
+	path, src := testWriteRotateConfig(t)
+	requests := kms.requests
+
+	code, out, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-new-key", "alias/new", "-dry-run", filepath.Dir(path))
+	if code != 0 {
+		t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+	}
+
+	for _, expected := range []string{
+		path + ":2: encryptedssm_parameter.db_password: would re-encrypt under alias/new",
+		path + ":19: encryptedssm_parameter.hosts: would re-encrypt under alias/new",
+		"2 resource(s) to re-encrypt, 0 skipped",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out)
+		}
+	}
+
+	// Each value is decrypted but nothing is encrypted
+	if n := kms.requests - requests; n != 3 {
+		t.Errorf("expected 3 KMS requests, got %d", n)
+	}
+
+	if content, err := ioutil.ReadFile(path); err != nil || string(content) != src {
+		t.Fatalf("expected the file to be unchanged, got:\n%s", content)
+	}
+}
+
+func TestRunRotate_skipped(t *testing.T) {
+	testCommandKMS(t)
+
+	ciphertext := testCommandEncrypt(t, encryptedssm.CipherOptions{KeyId: "alias/old"}, "MyStr0ngp@ss!")
+
+	testCases := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{
+			"variable key",
+			`encryption_key = var.key
+  encrypted_value = "CIPHERTEXT"`,
+			"encryption_key is not a literal value",
+		},
+		{
+			"variable bound name",
+			`name = var.name
+  encryption_key = "alias/old"
+  encrypted_value = "CIPHERTEXT"
+  bind_name_context = true`,
+			"name is not a literal value",
+		},
+		{
+			"null context value",
+			`encryption_key = "alias/old"
+  encrypted_value = "CIPHERTEXT"
+  encryption_context = { environment = null }`,
+			`encryption_context "environment" is null`,
+		},
+		{
+			"null list element",
+			`encryption_key = "alias/old"
+  encrypted_list = ["CIPHERTEXT", null]`,
+			"encrypted_list element 1 is null",
+		},
+		{
+			"null map value",
+			`encryption_key = "alias/old"
+  encrypted_fields = { username = "CIPHERTEXT", password = null }`,
+			`encrypted_fields "password" is null`,
+		},
+		{
+			"no ciphertext",
+			`encryption_key = "alias/old"`,
+			"no encrypted_value, encrypted_list, encrypted_fields or encrypted_values",
+		},
+		{
+			"undecryptable",
+			`encryption_key = "alias/old"
+  encrypted_value = "CIPHERTEXT"
+  encryption_context = { environment = "prod" }`,
+			"Error decrypting with KMS: InvalidCiphertextException",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := strings.Replace(`resource "encryptedssm_parameter" "test" {
+  `+tc.config+`
+}
+
+resource "encryptedssm_parameter" "age" {
+  encryption_scheme = "age"
+  encryption_key    = "alias/old"
+  encrypted_value   = "-----BEGIN AGE ENCRYPTED FILE-----"
+}
+
+resource "encryptedssm_parameter" "other" {
+  encryption_key  = "alias/other"
+  encrypted_value = var.other
+}
+`, "CIPHERTEXT", ciphertext, -1)
+
+			path := filepath.Join(t.TempDir(), "main.tf")
+			if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			code, out, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-old-key", "alias/old", "-new-key", "alias/new", path)
+			if code != 0 {
+				t.Fatalf("expected exit status 0, got %d: %s", code, errOut)
+			}
+
+			if expected := path + ":1: encryptedssm_parameter.test: skipped, " + tc.expected; !strings.Contains(out, expected) {
+				t.Errorf("expected output to contain %q, got:\n%s", expected, out)
+			}
+
+			// Resources of other schemes or keys are neither rotated nor skipped
+			if !strings.Contains(out, "0 resource(s) re-encrypted, 1 skipped") {
+				t.Errorf("expected a single skipped resource, got:\n%s", out)
+			}
+
+			if content, err := ioutil.ReadFile(path); err != nil || string(content) != src {
+				t.Fatalf("expected the file to be unchanged, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestRunRotate_errors(t *testing.T) {
+	testCommandKMS(t)
+
+	dir := t.TempDir()
+
+	invalid := filepath.Join(dir, "invalid.tf")
+	if err := ioutil.WriteFile(invalid, []byte(`resource "encryptedssm_parameter" {`), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	valid, _ := testWriteRotateConfig(t)
+
+	if code, _, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", valid); code != 1 || !strings.Contains(errOut, "-new-key is required") {
+		t.Errorf("expected -new-key to be required, got exit status %d: %s", code, errOut)
+	}
+
+	if code, _, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-new-key", "alias/new", filepath.Join(dir, "missing")); code != 1 || !strings.Contains(errOut, "no such file or directory") {
+		t.Errorf("expected a missing path to fail, got exit status %d: %s", code, errOut)
+	}
+
+	// Files that cannot be parsed are reported and the others still rotated
+	code, out, errOut := testRunCommand(t, runRotate, "", "-region", "us-east-1", "-new-key", "alias/new", invalid, valid)
+	if code != 1 || !strings.Contains(errOut, "Error: "+invalid) {
+		t.Errorf("expected an error for %s, got exit status %d: %s", invalid, code, errOut)
+	}
+
+	if !strings.Contains(out, "2 resource(s) re-encrypted, 0 skipped") {
+		t.Errorf("expected the valid file to be rotated, got:\n%s", out)
+	}
+}
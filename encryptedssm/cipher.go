@@ -0,0 +1,93 @@
+package encryptedssm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"hash"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// ParameterNameContextKey is the encryption context key the parameter name is
+// bound to when bind_name_context is enabled.
+const ParameterNameContextKey = "PARAMETER_NAME"
+
+// CipherOptions holds the KMS arguments a value is encrypted or decrypted
+// with, matching the encryption arguments of the encryptedssm_parameter
+// resource.
+type CipherOptions struct {
+	KeyId               string
+	EncryptionAlgorithm string
+	EncryptionContext   map[string]string
+}
+
+// EncryptValue encrypts plaintext with KMS into the format accepted by
+// encrypted_value. client is the value returned by Config.Client.
+func EncryptValue(client interface{}, opts CipherOptions, plaintext []byte) (string, error) {
+	input := &kms.EncryptInput{
+		KeyId:     aws.String(opts.KeyId),
+		Plaintext: plaintext,
+	}
+
+	if opts.EncryptionAlgorithm != "" {
+		input.EncryptionAlgorithm = aws.String(opts.EncryptionAlgorithm)
+	}
+
+	if len(opts.EncryptionContext) > 0 {
+		input.EncryptionContext = aws.StringMap(opts.EncryptionContext)
+	}
+
+	return encryptValue(input, client)
+}
+
+// DecryptValue decrypts a value in the format accepted by encrypted_value.
+// client is the value returned by Config.Client.
+func DecryptValue(client interface{}, opts CipherOptions, ciphertext string) ([]byte, error) {
+	return (&kmsDecryptor{client: client.(*AWSClient)}).decrypt(ciphertext, opts)
+}
+
+// EncryptValueWithPublicKey encrypts plaintext locally with the PEM or DER
+// encoded public key of an asymmetric KMS key, as returned by
+// kms:GetPublicKey, so no AWS credentials are needed. The result can be
+// decrypted by KMS with the same RSAES_OAEP algorithm.
+func EncryptValueWithPublicKey(publicKey []byte, algorithm string, plaintext []byte) (string, error) {
+	var h hash.Hash
+
+	switch algorithm {
+	case kms.EncryptionAlgorithmSpecRsaesOaepSha1:
+		h = sha1.New()
+	case kms.EncryptionAlgorithmSpecRsaesOaepSha256:
+		h = sha256.New()
+	default:
+		return "", fmt.Errorf("encryption algorithm (%s) is not supported with a public key, expected %s or %s",
+			algorithm, kms.EncryptionAlgorithmSpecRsaesOaepSha1, kms.EncryptionAlgorithmSpecRsaesOaepSha256)
+	}
+
+	if block, _ := pem.Decode(publicKey); block != nil {
+		publicKey = block.Bytes
+	}
+
+	key, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("error parsing public key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("public key is a %T, expected an RSA key", key)
+	}
+
+	ciphertext, err := rsa.EncryptOAEP(h, rand.Reader, rsaKey, plaintext, nil)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting with public key: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
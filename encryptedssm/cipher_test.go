@@ -0,0 +1,83 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+func TestEncryptValue_roundTrip(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+
+	opts := CipherOptions{
+		KeyId:             "alias/test",
+		EncryptionContext: map[string]string{"environment": "prod"},
+	}
+
+	for _, plaintext := range [][]byte{[]byte("MyStr0ngp@ss!"), []byte(strings.Repeat("x", 8192))} {
+		ciphertext, err := EncryptValue(client, opts, plaintext)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		decrypted, err := DecryptValue(client, opts, ciphertext)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("expected decrypted value to match plaintext")
+		}
+
+		if _, err := DecryptValue(client, CipherOptions{KeyId: "alias/test"}, ciphertext); err == nil {
+			t.Fatalf("expected error decrypting without the encryption context")
+		}
+	}
+}
+
+func TestEncryptValueWithPublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	publicKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	for _, publicKey := range [][]byte{der, publicKeyPem} {
+		ciphertext, err := EncryptValueWithPublicKey(publicKey, kms.EncryptionAlgorithmSpecRsaesOaepSha256, []byte("MyStr0ngp@ss!"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		plaintext, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, blob, nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if string(plaintext) != "MyStr0ngp@ss!" {
+			t.Fatalf("expected decrypted value to match plaintext")
+		}
+	}
+
+	if _, err := EncryptValueWithPublicKey(der, kms.EncryptionAlgorithmSpecSymmetricDefault, []byte("x")); err == nil {
+		t.Fatalf("expected error encrypting with %s", kms.EncryptionAlgorithmSpecSymmetricDefault)
+	}
+}
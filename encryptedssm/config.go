@@ -3,10 +3,15 @@ package encryptedssm
 import (
 	"fmt"
 	"log"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	awsbase "github.com/hashicorp/aws-sdk-go-base"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/logging"
 	"github.com/terraform-providers/terraform-provider-aws/version"
@@ -33,19 +38,37 @@ type Config struct {
 	AllowedAccountIds   []string
 	ForbiddenAccountIds []string
 
-	Endpoints map[string]string
-	Insecure  bool
+	AgeConfig         *AgeConfig
+	DefaultTagsConfig *DefaultConfig
+	Endpoints         map[string]string
+	IgnoreTagsConfig  *IgnoreConfig
+	Insecure          bool
+	PgpConfig         *PgpConfig
+	VaultConfig       *VaultConfig
 
-	SkipCredsValidation  bool
-	SkipRegionValidation bool
+	SkipCredsValidation     bool
+	SkipMetadataApiCheck    bool
+	SkipRegionValidation    bool
+	SkipRequestingAccountId bool
 
 	terraformVersion string
 }
 
+// AWSClient holds the service clients as interfaces so tests can substitute
+// in-memory implementations.
 type AWSClient struct {
-	ssmconn          *ssm.SSM
-	kmsconn          *kms.KMS
-	IgnoreTagsConfig *IgnoreConfig
+	ssmconn            ssmiface.SSMAPI
+	kmsconn            kmsiface.KMSAPI
+	secretsmanagerconn secretsmanageriface.SecretsManagerAPI
+	decryptors         map[string]decryptor
+	DefaultTagsConfig  *DefaultConfig
+	IgnoreTagsConfig   *IgnoreConfig
+
+	// Plaintexts and KMS keys already looked up, as a plan decrypts each
+	// changed ciphertext and describes each key in several diff steps
+	cacheMu    sync.Mutex
+	plaintexts map[string][]byte
+	kmsKeys    map[string]*kms.KeyMetadata
 }
 
 // TagData represents the data associated with a resource tag key.
@@ -67,6 +90,22 @@ type TagData struct {
 
 type KeyValueTags map[string]*TagData
 
+// DefaultConfig contains tags to default across all resources.
+type DefaultConfig struct {
+	Tags KeyValueTags
+}
+
+// MergeTags returns the result of Merge() on the given DefaultConfig.Tags
+// with the KeyValueTags provided as an argument, overriding the value of any
+// tag with a matching key.
+func (dc *DefaultConfig) MergeTags(tags KeyValueTags) KeyValueTags {
+	if dc == nil || dc.Tags == nil {
+		return tags
+	}
+
+	return dc.Tags.Merge(tags)
+}
+
 // IgnoreConfig contains various options for removing resource tags.
 type IgnoreConfig struct {
 	Keys        KeyValueTags
@@ -83,7 +122,39 @@ func (c *Config) Client() (interface{}, error) {
 		}
 	}
 
-	awsbaseConfig := &awsbase.Config{
+	sess, accountID, _, err := awsbase.GetSessionWithAccountIDAndPartition(c.awsbaseConfig())
+	if err != nil {
+		return nil, fmt.Errorf("error configuring Terraform AWS Provider: %w", err)
+	}
+
+	if accountID == "" {
+		log.Printf("[WARN] AWS account ID not found for provider. See https://www.terraform.io/docs/providers/aws/index.html#skip_requesting_account_id for implications.")
+	}
+
+	if err := awsbase.ValidateAccountID(accountID, c.AllowedAccountIds, c.ForbiddenAccountIds); err != nil {
+		return nil, err
+	}
+
+	client := &AWSClient{
+		ssmconn:            ssm.New(sess.Copy(&aws.Config{Endpoint: aws.String(c.Endpoints["ssm"])})),
+		kmsconn:            kms.New(sess.Copy(&aws.Config{Endpoint: aws.String(c.Endpoints["kms"])})),
+		secretsmanagerconn: secretsmanager.New(sess.Copy(&aws.Config{Endpoint: aws.String(c.Endpoints["secretsmanager"])})),
+
+		DefaultTagsConfig: c.DefaultTagsConfig,
+		IgnoreTagsConfig:  c.IgnoreTagsConfig,
+	}
+
+	if err := c.configureDecryptors(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// awsbaseConfig returns the aws-sdk-go-base configuration the session is
+// created from.
+func (c *Config) awsbaseConfig() *awsbase.Config {
+	return &awsbase.Config{
 		AccessKey:                   c.AccessKey,
 		AssumeRoleARN:               c.AssumeRoleARN,
 		AssumeRoleDurationSeconds:   c.AssumeRoleDurationSeconds,
@@ -97,10 +168,16 @@ func (c *Config) Client() (interface{}, error) {
 		CallerName:                  "Terraform encryptedssm Provider",
 		CredsFilename:               c.CredsFilename,
 		DebugLogging:                logging.IsDebugOrHigher(),
+		IamEndpoint:                 c.Endpoints["iam"],
+		Insecure:                    c.Insecure,
 		MaxRetries:                  c.MaxRetries,
 		Profile:                     c.Profile,
 		Region:                      c.Region,
 		SecretKey:                   c.SecretKey,
+		SkipCredsValidation:         c.SkipCredsValidation,
+		SkipMetadataApiCheck:        c.SkipMetadataApiCheck,
+		SkipRequestingAccountId:     c.SkipRequestingAccountId,
+		StsEndpoint:                 c.Endpoints["sts"],
 		Token:                       c.Token,
 		UserAgentProducts: []*awsbase.UserAgentProduct{
 			{Name: "APN", Version: "1.0"},
@@ -110,24 +187,40 @@ func (c *Config) Client() (interface{}, error) {
 			{Name: "terraform-provider-encryptedssm", Version: version.ProviderVersion, Extra: []string{"+https://registry.terraform.io/providers/hashicorp/aws"}},
 		},
 	}
+}
 
-	sess, accountID, _, err := awsbase.GetSessionWithAccountIDAndPartition(awsbaseConfig)
-	if err != nil {
-		return nil, fmt.Errorf("error configuring Terraform AWS Provider: %w", err)
-	}
+// configureDecryptors adds the decryptors of the configured encryption
+// schemes to the client. Identities encrypted with KMS are decrypted with the
+// client.
+func (c *Config) configureDecryptors(client *AWSClient) error {
+	client.decryptors = make(map[string]decryptor)
 
-	if accountID == "" {
-		log.Printf("[WARN] AWS account ID not found for provider. See https://www.terraform.io/docs/providers/aws/index.html#skip_requesting_account_id for implications.")
+	if c.AgeConfig != nil {
+		d, err := newAgeDecryptor(c.AgeConfig, client)
+		if err != nil {
+			return err
+		}
+
+		client.decryptors[encryptionSchemeAge] = d
 	}
 
-	if err := awsbase.ValidateAccountID(accountID, c.AllowedAccountIds, c.ForbiddenAccountIds); err != nil {
-		return nil, err
+	if c.PgpConfig != nil {
+		d, err := newPgpDecryptor(c.PgpConfig, client)
+		if err != nil {
+			return err
+		}
+
+		client.decryptors[encryptionSchemePgp] = d
 	}
 
-	client := &AWSClient{
-		ssmconn: ssm.New(sess.Copy(&aws.Config{Endpoint: aws.String(c.Endpoints["ssm"])})),
-		kmsconn: kms.New(sess.Copy(&aws.Config{Endpoint: aws.String(c.Endpoints["kms"])})),
+	if c.VaultConfig != nil {
+		d, err := newVaultDecryptor(c.VaultConfig)
+		if err != nil {
+			return err
+		}
+
+		client.decryptors[encryptionSchemeVault] = d
 	}
 
-	return client, nil
+	return nil
 }
@@ -0,0 +1,80 @@
+package encryptedssm
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceAwsKmsPublicKey() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsKmsPublicKeyRead,
+
+		Schema: map[string]*schema.Schema{
+			"key_id": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"customer_master_key_spec": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"encryption_algorithms": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"key_usage": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"public_key": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"public_key_pem": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceAwsKmsPublicKeyRead(d *schema.ResourceData, meta interface{}) error {
+	kmsconn := meta.(*AWSClient).kmsconn
+	keyId := d.Get("key_id").(string)
+
+	log.Printf("[DEBUG] Reading KMS public key: %s", keyId)
+
+	resp, err := kmsconn.GetPublicKey(&kms.GetPublicKeyInput{
+		KeyId: aws.String(keyId),
+	})
+	if err != nil {
+		return fmt.Errorf("error reading KMS public key (%s): %w", keyId, err)
+	}
+
+	d.SetId(aws.StringValue(resp.KeyId))
+	d.Set("arn", resp.KeyId)
+	d.Set("customer_master_key_spec", resp.CustomerMasterKeySpec)
+	d.Set("key_usage", resp.KeyUsage)
+	d.Set("public_key", base64.StdEncoding.EncodeToString(resp.PublicKey))
+	d.Set("public_key_pem", string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: resp.PublicKey,
+	})))
+
+	if err := d.Set("encryption_algorithms", aws.StringValueSlice(resp.EncryptionAlgorithms)); err != nil {
+		return fmt.Errorf("error setting encryption_algorithms: %s", err)
+	}
+
+	return nil
+}
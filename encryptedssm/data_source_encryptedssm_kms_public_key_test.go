@@ -0,0 +1,51 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestDataSourceAwsKmsPublicKey_basic(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	dataSourceName := "data.encryptedssm_kms_public_key.test"
+	keyArn := kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceAwsKmsPublicKeyConfig("alias/test-rsa"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "id", keyArn),
+					resource.TestCheckResourceAttr(dataSourceName, "arn", keyArn),
+					resource.TestCheckResourceAttr(dataSourceName, "customer_master_key_spec", kms.CustomerMasterKeySpecRsa2048),
+					resource.TestCheckResourceAttr(dataSourceName, "key_usage", kms.KeyUsageTypeEncryptDecrypt),
+					resource.TestCheckResourceAttr(dataSourceName, "encryption_algorithms.#", "2"),
+					resource.TestCheckResourceAttrSet(dataSourceName, "public_key"),
+					resource.TestMatchResourceAttr(dataSourceName, "public_key_pem", regexp.MustCompile(`^-----BEGIN PUBLIC KEY-----\n`)),
+				),
+			},
+			{
+				Config:      testDataSourceAwsKmsPublicKeyConfig("alias/test"),
+				ExpectError: regexp.MustCompile(`error reading KMS public key \(alias/test\)`),
+			},
+		},
+	})
+}
+
+func testDataSourceAwsKmsPublicKeyConfig(keyId string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+data "encryptedssm_kms_public_key" "test" {
+  key_id = %[2]q
+}
+`, testRegion, keyId)
+}
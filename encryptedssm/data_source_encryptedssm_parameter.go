@@ -0,0 +1,151 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceAwsSsmParameter() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsSsmParameterRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"encryption_key": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"encryption_algorithm": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					kms.EncryptionAlgorithmSpecSymmetricDefault,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+				}, false),
+			},
+			"encryption_context": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"bind_name_context": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"encrypted_value": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"type": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"tier": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"data_type": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"version": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"tags": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error {
+	ssmconn := meta.(*AWSClient).ssmconn
+	ignoreTagsConfig := meta.(*AWSClient).IgnoreTagsConfig
+
+	name := d.Get("name").(string)
+
+	log.Printf("[DEBUG] Reading SSM Parameter: %s", name)
+
+	resp, err := ssmconn.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error reading SSM Parameter (%s): %w", name, err)
+	}
+
+	param := resp.Parameter
+
+	input := &kms.EncryptInput{
+		KeyId:     aws.String(d.Get("encryption_key").(string)),
+		Plaintext: []byte(aws.StringValue(param.Value)),
+	}
+
+	if v, ok := d.GetOk("encryption_algorithm"); ok {
+		input.EncryptionAlgorithm = aws.String(v.(string))
+	}
+
+	if v := expandEncryptionContext(d); len(v) > 0 {
+		input.EncryptionContext = v
+	}
+
+	encryptedValue, err := encryptValue(input, meta)
+	if err != nil {
+		return fmt.Errorf("error encrypting SSM Parameter (%s): %s", name, err)
+	}
+
+	d.SetId(aws.StringValue(param.Name))
+	d.Set("encrypted_value", encryptedValue)
+	d.Set("arn", param.ARN)
+	d.Set("type", param.Type)
+	d.Set("version", param.Version)
+	d.Set("data_type", param.DataType)
+
+	describeResp, err := ssmconn.DescribeParameters(&ssm.DescribeParametersInput{
+		ParameterFilters: []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("Name"),
+				Option: aws.String("Equals"),
+				Values: []*string{param.Name},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error describing SSM parameter: %s", err)
+	}
+
+	d.Set("tier", ssm.ParameterTierStandard)
+	if describeResp != nil && len(describeResp.Parameters) > 0 && describeResp.Parameters[0] != nil && describeResp.Parameters[0].Tier != nil {
+		d.Set("tier", describeResp.Parameters[0].Tier)
+	}
+
+	tags, err := SsmListTags(ssmconn, name, ssm.ResourceTypeForTaggingParameter)
+
+	if err != nil {
+		return fmt.Errorf("error listing tags for SSM Parameter (%s): %s", name, err)
+	}
+
+	if err := d.Set("tags", tags.IgnoreAws().IgnoreConfig(ignoreTagsConfig).Map()); err != nil {
+		return fmt.Errorf("error setting tags: %s", err)
+	}
+
+	return nil
+}
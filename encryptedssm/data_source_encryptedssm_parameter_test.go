@@ -0,0 +1,128 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const testSsmParameterSourceName = "/encryptedssm/source"
+
+func TestDataSourceAwsSsmParameter_basic(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	dataSourceName := "data.encryptedssm_parameter.test"
+	config := testDataSourceAwsSsmParameterConfig()
+
+	testPutFakeSsmParameter(t, ssmconn, testSsmParameterSourceName, ssm.ParameterTypeSecureString, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_value", "MyStr0ngp@ss!"),
+					resource.TestCheckResourceAttr(dataSourceName, "type", ssm.ParameterTypeSecureString),
+					resource.TestCheckResourceAttr(dataSourceName, "version", "1"),
+					resource.TestCheckResourceAttr(dataSourceName, "arn", ssmconn.arn(testSsmParameterSourceName)),
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected value to be copied, got %d bytes", len(p.Value))
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				// The data source returns a new ciphertext of the same value
+				Config:   config,
+				PlanOnly: true,
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterSourceName, func(p *fakeSSMParameter) {
+						p.Value = "changed at the source"
+						p.Version++
+					})
+				},
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_value", "changed at the source"),
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "changed at the source" {
+							return fmt.Errorf("expected changed value to be copied")
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+// testPutFakeSsmParameter creates a parameter outside of Terraform.
+func testPutFakeSsmParameter(t *testing.T, ssmconn *fakeSSM, name, parameterType, value string) {
+	t.Helper()
+
+	_, err := ssmconn.PutParameter(&ssm.PutParameterInput{
+		Name:  aws.String(name),
+		Type:  aws.String(parameterType),
+		Value: aws.String(value),
+	})
+	if err != nil {
+		t.Fatalf("error putting SSM Parameter (%s): %s", name, err)
+	}
+}
+
+// testCheckResourceAttrDecrypts checks an attribute holds a fakeKMS
+// ciphertext of the expected plaintext.
+func testCheckResourceAttrDecrypts(kmsconn *fakeKMS, name, key, expected string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("not found: %s", name)
+		}
+
+		v, ok := rs.Primary.Attributes[key]
+		if !ok {
+			return fmt.Errorf("%s: attribute %s not found", name, key)
+		}
+
+		plaintext, err := kmsconn.decrypt(v)
+		if err != nil {
+			return fmt.Errorf("%s: error decrypting %s: %s", name, key, err)
+		}
+
+		if plaintext != expected {
+			return fmt.Errorf("%s: expected %s to decrypt to the expected value, got %d bytes", name, key, len(plaintext))
+		}
+
+		return nil
+	}
+}
+
+func testDataSourceAwsSsmParameterConfig() string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+data "encryptedssm_parameter" "test" {
+  name           = %[2]q
+  encryption_key = "alias/test"
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[3]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  encrypted_value = data.encryptedssm_parameter.test.encrypted_value
+}
+`, testRegion, testSsmParameterSourceName, testSsmParameterName)
+}
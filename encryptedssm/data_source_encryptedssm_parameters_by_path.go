@@ -0,0 +1,160 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceAwsSsmParametersByPath() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsSsmParametersByPathRead,
+
+		Schema: map[string]*schema.Schema{
+			"path": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"recursive": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"encryption_key": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"encryption_algorithm": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					kms.EncryptionAlgorithmSpecSymmetricDefault,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+				}, false),
+			},
+			"encryption_context": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"bind_name_context": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"arns": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"types": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"versions": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+			},
+			"encrypted_values": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceAwsSsmParametersByPathRead(d *schema.ResourceData, meta interface{}) error {
+	ssmconn := meta.(*AWSClient).ssmconn
+
+	path := d.Get("path").(string)
+
+	log.Printf("[DEBUG] Reading SSM Parameters by path: %s", path)
+
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(d.Get("recursive").(bool)),
+		WithDecryption: aws.Bool(true),
+	}
+
+	var parameters []*ssm.Parameter
+	err := ssmconn.GetParametersByPathPages(input, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		parameters = append(parameters, page.Parameters...)
+		return !lastPage
+	})
+	if err != nil {
+		return fmt.Errorf("error reading SSM Parameters by path (%s): %w", path, err)
+	}
+
+	names := make([]string, 0, len(parameters))
+	arns := make([]string, 0, len(parameters))
+	types := make([]string, 0, len(parameters))
+	versions := make([]int, 0, len(parameters))
+	encryptedValues := make([]string, 0, len(parameters))
+
+	for _, param := range parameters {
+		name := aws.StringValue(param.Name)
+
+		encryptInput := &kms.EncryptInput{
+			KeyId:     aws.String(d.Get("encryption_key").(string)),
+			Plaintext: []byte(aws.StringValue(param.Value)),
+		}
+
+		if v, ok := d.GetOk("encryption_algorithm"); ok {
+			encryptInput.EncryptionAlgorithm = aws.String(v.(string))
+		}
+
+		encryptionContext := expandParameterEncryptionContext(d, name)
+		if len(encryptionContext) > 0 {
+			encryptInput.EncryptionContext = encryptionContext
+		}
+
+		encryptedValue, err := encryptValue(encryptInput, meta)
+		if err != nil {
+			return fmt.Errorf("error encrypting SSM Parameter (%s): %s", name, err)
+		}
+
+		names = append(names, name)
+		arns = append(arns, aws.StringValue(param.ARN))
+		types = append(types, aws.StringValue(param.Type))
+		versions = append(versions, int(aws.Int64Value(param.Version)))
+		encryptedValues = append(encryptedValues, encryptedValue)
+	}
+
+	d.SetId(path)
+
+	if err := d.Set("names", names); err != nil {
+		return fmt.Errorf("error setting names: %s", err)
+	}
+
+	if err := d.Set("arns", arns); err != nil {
+		return fmt.Errorf("error setting arns: %s", err)
+	}
+
+	if err := d.Set("types", types); err != nil {
+		return fmt.Errorf("error setting types: %s", err)
+	}
+
+	if err := d.Set("versions", versions); err != nil {
+		return fmt.Errorf("error setting versions: %s", err)
+	}
+
+	if err := d.Set("encrypted_values", encryptedValues); err != nil {
+		return fmt.Errorf("error setting encrypted_values: %s", err)
+	}
+
+	return nil
+}
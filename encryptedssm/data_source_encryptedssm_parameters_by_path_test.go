@@ -0,0 +1,62 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestDataSourceAwsSsmParametersByPath_basic(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	dataSourceName := "data.encryptedssm_parameters_by_path.test"
+
+	testPutFakeSsmParameter(t, ssmconn, "/encryptedssm/app/a", ssm.ParameterTypeSecureString, "value-a")
+	testPutFakeSsmParameter(t, ssmconn, "/encryptedssm/app/b", ssm.ParameterTypeString, "value-b")
+	testPutFakeSsmParameter(t, ssmconn, "/encryptedssm/app/sub/c", ssm.ParameterTypeSecureString, "value-c")
+	testPutFakeSsmParameter(t, ssmconn, "/encryptedssm/other", ssm.ParameterTypeSecureString, "other")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testDataSourceAwsSsmParametersByPathConfig(false),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "names.#", "2"),
+					resource.TestCheckResourceAttr(dataSourceName, "names.0", "/encryptedssm/app/a"),
+					resource.TestCheckResourceAttr(dataSourceName, "names.1", "/encryptedssm/app/b"),
+					resource.TestCheckResourceAttr(dataSourceName, "types.1", ssm.ParameterTypeString),
+					resource.TestCheckResourceAttr(dataSourceName, "versions.0", "1"),
+					resource.TestCheckResourceAttr(dataSourceName, "arns.0", ssmconn.arn("/encryptedssm/app/a")),
+					resource.TestCheckResourceAttr(dataSourceName, "encrypted_values.#", "2"),
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_values.0", "value-a"),
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_values.1", "value-b"),
+				),
+			},
+			{
+				Config: testDataSourceAwsSsmParametersByPathConfig(true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "names.#", "3"),
+					resource.TestCheckResourceAttr(dataSourceName, "names.2", "/encryptedssm/app/sub/c"),
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_values.2", "value-c"),
+				),
+			},
+		},
+	})
+}
+
+func testDataSourceAwsSsmParametersByPathConfig(recursive bool) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+data "encryptedssm_parameters_by_path" "test" {
+  path           = "/encryptedssm/app"
+  recursive      = %[2]t
+  encryption_key = "alias/test"
+}
+`, testRegion, recursive)
+}
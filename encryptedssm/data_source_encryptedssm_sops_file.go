@@ -0,0 +1,112 @@
+package encryptedssm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func dataSourceAwsSopsFile() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAwsSopsFileRead,
+
+		Schema: map[string]*schema.Schema{
+			"source": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"source", "content"},
+			},
+			"content": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"source", "content"},
+			},
+			"encryption_key": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"encryption_algorithm": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					kms.EncryptionAlgorithmSpecSymmetricDefault,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+				}, false),
+			},
+			"encryption_context": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"encrypted_values": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dataSourceAwsSopsFileRead(d *schema.ResourceData, meta interface{}) error {
+	var data []byte
+
+	if v, ok := d.GetOk("source"); ok {
+		source := v.(string)
+
+		log.Printf("[DEBUG] Reading SOPS file: %s", source)
+
+		var err error
+		if data, err = ioutil.ReadFile(source); err != nil {
+			return fmt.Errorf("error reading SOPS file (%s): %w", source, err)
+		}
+	} else {
+		data = []byte(d.Get("content").(string))
+	}
+
+	leaves, err := decryptSopsDocument(data, meta)
+	if err != nil {
+		return err
+	}
+
+	encryptionContext := make(map[string]*string)
+	for k, v := range d.Get("encryption_context").(map[string]interface{}) {
+		encryptionContext[k] = aws.String(v.(string))
+	}
+
+	encryptedValues := make(map[string]string, len(leaves))
+	for path, plaintext := range leaves {
+		encryptInput := &kms.EncryptInput{
+			KeyId:     aws.String(d.Get("encryption_key").(string)),
+			Plaintext: []byte(plaintext),
+		}
+
+		if v, ok := d.GetOk("encryption_algorithm"); ok {
+			encryptInput.EncryptionAlgorithm = aws.String(v.(string))
+		}
+
+		if len(encryptionContext) > 0 {
+			encryptInput.EncryptionContext = encryptionContext
+		}
+
+		if encryptedValues[path], err = encryptValue(encryptInput, meta); err != nil {
+			return fmt.Errorf("error encrypting SOPS value (%s): %s", path, err)
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	d.SetId(hex.EncodeToString(sum[:]))
+
+	if err := d.Set("encrypted_values", encryptedValues); err != nil {
+		return fmt.Errorf("error setting encrypted_values: %s", err)
+	}
+
+	return nil
+}
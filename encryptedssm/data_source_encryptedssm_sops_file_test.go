@@ -0,0 +1,106 @@
+package encryptedssm
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestDataSourceAwsSopsFile_basic(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	dataSourceName := "data.encryptedssm_sops_file.test"
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyArn, _ := kmsconn.keyArn(aws.String("alias/test"))
+	encryptedDataKey, err := kmsconn.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(keyArn),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	source := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	document := fmt.Sprintf(`db:
+  user: %s
+  password: %s
+sops:
+  kms:
+  - arn: %s
+    enc: %s
+  lastmodified: "2024-03-01T12:00:00Z"
+  mac: %s
+  unencrypted_suffix: _unencrypted
+  version: 3.7.1
+`,
+		testSopsEncrypt(t, dataKey, "app", "db:user:"),
+		testSopsEncrypt(t, dataKey, "MyStr0ngp@ss!", "db:password:"),
+		keyArn,
+		base64.StdEncoding.EncodeToString(encryptedDataKey.CiphertextBlob),
+		testSopsMac(t, dataKey, "2024-03-01T12:00:00Z", "app", "MyStr0ngp@ss!"),
+	)
+
+	if err := ioutil.WriteFile(source, []byte(document), 0600); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config := testDataSourceAwsSopsFileConfig(source)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(dataSourceName, "encrypted_values.%", "2"),
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_values.db/user", "app"),
+					testCheckResourceAttrDecrypts(kmsconn, dataSourceName, "encrypted_values.db/password", "MyStr0ngp@ss!"),
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						return nil
+					}),
+				),
+			},
+			{
+				// The data source returns new ciphertexts of the same values
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func testDataSourceAwsSopsFileConfig(source string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+data "encryptedssm_sops_file" "test" {
+  source         = %[2]q
+  encryption_key = "alias/test"
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[3]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  encrypted_value = data.encryptedssm_sops_file.test.encrypted_values["db/password"]
+}
+`, testRegion, source, testSsmParameterName)
+}
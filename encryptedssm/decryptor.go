@@ -0,0 +1,136 @@
+package encryptedssm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+const (
+	encryptionSchemeAge   = "age"
+	encryptionSchemeKms   = "kms"
+	encryptionSchemePgp   = "pgp"
+	encryptionSchemeVault = "vault"
+)
+
+// encryptionSchemes are the valid values of encryption_scheme.
+var encryptionSchemes = []string{
+	encryptionSchemeAge,
+	encryptionSchemeKms,
+	encryptionSchemePgp,
+	encryptionSchemeVault,
+}
+
+// decryptor decrypts the values of one encryption_scheme. Decryptors other
+// than KMS are created from the provider configuration and held by the
+// AWSClient.
+type decryptor interface {
+	// decrypt returns the plaintext of a value in the format accepted by
+	// encrypted_value. Options a scheme does not support are ignored.
+	decrypt(ciphertext string, opts CipherOptions) ([]byte, error)
+}
+
+// decryptor returns the decryptor for an encryption_scheme.
+func (c *AWSClient) decryptor(scheme string) (decryptor, error) {
+	if scheme == "" || scheme == encryptionSchemeKms {
+		return &kmsDecryptor{client: c}, nil
+	}
+
+	if d, ok := c.decryptors[scheme]; ok {
+		return d, nil
+	}
+
+	return nil, fmt.Errorf("encryption_scheme %q requires the provider %s block to be configured", scheme, scheme)
+}
+
+// decrypt decrypts a ciphertext with the decryptor of an encryption_scheme,
+// reusing the plaintext of an earlier decryption of the same ciphertext with
+// the same options. The provider only lives for a single Terraform command,
+// during which a plan decrypts a changed ciphertext to suppress equivalent
+// ciphertexts, verify it, validate the value and compare the value hash.
+func (c *AWSClient) decrypt(scheme, ciphertext string, opts CipherOptions) ([]byte, error) {
+	if scheme == "" {
+		scheme = encryptionSchemeKms
+	}
+
+	dec, err := c.decryptor(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := json.Marshal([]interface{}{scheme, opts, ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(input)
+	cacheKey := string(sum[:])
+
+	c.cacheMu.Lock()
+	plaintext, ok := c.plaintexts[cacheKey]
+	c.cacheMu.Unlock()
+
+	if ok {
+		return plaintext, nil
+	}
+
+	plaintext, err = dec.decrypt(ciphertext, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cacheMu.Lock()
+	if c.plaintexts == nil {
+		c.plaintexts = make(map[string][]byte)
+	}
+	c.plaintexts[cacheKey] = plaintext
+	c.cacheMu.Unlock()
+
+	return plaintext, nil
+}
+
+// kmsDecryptor decrypts KMS ciphertext blobs and envelopes.
+type kmsDecryptor struct {
+	client *AWSClient
+}
+
+func (k *kmsDecryptor) decrypt(ciphertext string, opts CipherOptions) ([]byte, error) {
+	input := &kms.DecryptInput{
+		KeyId: aws.String(opts.KeyId),
+	}
+
+	if opts.EncryptionAlgorithm != "" {
+		input.EncryptionAlgorithm = aws.String(opts.EncryptionAlgorithm)
+	}
+
+	if len(opts.EncryptionContext) > 0 {
+		input.EncryptionContext = aws.StringMap(opts.EncryptionContext)
+	}
+
+	return decryptValue(ciphertext, input, k.client)
+}
+
+// providerSecret returns a secret configured in the provider either directly,
+// as the path of a file holding it or as a base64 KMS ciphertext, decrypted
+// with the client.
+func providerSecret(value, file, encrypted string, client *AWSClient) ([]byte, error) {
+	switch {
+	case value != "":
+		return []byte(value), nil
+	case file != "":
+		path, err := homedir.Expand(file)
+		if err != nil {
+			return nil, err
+		}
+
+		return ioutil.ReadFile(path)
+	case encrypted != "":
+		return decryptValue(encrypted, &kms.DecryptInput{}, client)
+	}
+
+	return nil, fmt.Errorf("no secret configured")
+}
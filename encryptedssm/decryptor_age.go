@@ -0,0 +1,61 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// AgeConfig holds the age identity the age encryption_scheme decrypts with.
+type AgeConfig struct {
+	Identity          string
+	IdentityFile      string
+	EncryptedIdentity string
+}
+
+// ageDecryptor decrypts age ciphertexts, armored or base64 encoded, with the
+// X25519 identities of an identity file.
+type ageDecryptor struct {
+	identities []age.Identity
+}
+
+func newAgeDecryptor(config *AgeConfig, client *AWSClient) (*ageDecryptor, error) {
+	identity, err := providerSecret(config.Identity, config.IdentityFile, config.EncryptedIdentity, client)
+	if err != nil {
+		return nil, fmt.Errorf("error reading age identity: %s", err)
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(identity))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing age identity: %s", err)
+	}
+
+	return &ageDecryptor{identities: identities}, nil
+}
+
+func (a *ageDecryptor) decrypt(ciphertext string, _ CipherOptions) ([]byte, error) {
+	var src io.Reader
+
+	if ciphertext = strings.TrimSpace(ciphertext); strings.HasPrefix(ciphertext, armor.Header) {
+		src = armor.NewReader(strings.NewReader(ciphertext))
+	} else {
+		blob, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("age ciphertext is neither armored nor valid base64: %s", err)
+		}
+		src = bytes.NewReader(blob)
+	}
+
+	r, err := age.Decrypt(src, a.identities...)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with age: %s", err)
+	}
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,99 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	pgperrors "golang.org/x/crypto/openpgp/errors"
+)
+
+// PgpConfig holds the OpenPGP private key the pgp encryption_scheme decrypts
+// with.
+type PgpConfig struct {
+	PrivateKey          string
+	PrivateKeyFile      string
+	EncryptedPrivateKey string
+	Passphrase          string
+}
+
+// pgpDecryptor decrypts OpenPGP messages, armored or base64 encoded, with a
+// private key ring. Signatures on the messages are not verified.
+//
+// golang.org/x/crypto/openpgp is frozen upstream but kept over its maintained
+// fork, which would add github.com/cloudflare/circl to the vendored
+// dependencies, as it is only used to read keys and decrypt messages, not to
+// generate keys or sign where its outdated defaults matter. It reads RSA and
+// ElGamal keys but not the Curve25519 keys recent GnuPG versions generate.
+type pgpDecryptor struct {
+	keyring openpgp.EntityList
+}
+
+func newPgpDecryptor(config *PgpConfig, client *AWSClient) (*pgpDecryptor, error) {
+	key, err := providerSecret(config.PrivateKey, config.PrivateKeyFile, config.EncryptedPrivateKey, client)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OpenPGP private key: %s", err)
+	}
+
+	var keyring openpgp.EntityList
+	if bytes.Contains(key, []byte("-----BEGIN PGP")) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(key))
+	}
+	if _, ok := err.(pgperrors.UnsupportedError); ok {
+		return nil, fmt.Errorf("error parsing OpenPGP private key: %s, only RSA and ElGamal keys are supported", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OpenPGP private key: %s", err)
+	}
+
+	passphrase := []byte(config.Passphrase)
+	for _, entity := range keyring {
+		if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+			if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("error decrypting OpenPGP private key: %s", err)
+			}
+		}
+
+		for _, subkey := range entity.Subkeys {
+			if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+				if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+					return nil, fmt.Errorf("error decrypting OpenPGP private key: %s", err)
+				}
+			}
+		}
+	}
+
+	return &pgpDecryptor{keyring: keyring}, nil
+}
+
+func (p *pgpDecryptor) decrypt(ciphertext string, _ CipherOptions) ([]byte, error) {
+	var src io.Reader
+
+	if ciphertext = strings.TrimSpace(ciphertext); strings.HasPrefix(ciphertext, "-----BEGIN PGP MESSAGE-----") {
+		block, err := armor.Decode(strings.NewReader(ciphertext))
+		if err != nil {
+			return nil, fmt.Errorf("error decoding OpenPGP message: %s", err)
+		}
+		src = block.Body
+	} else {
+		blob, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("OpenPGP ciphertext is neither armored nor valid base64: %s", err)
+		}
+		src = bytes.NewReader(blob)
+	}
+
+	md, err := openpgp.ReadMessage(src, p.keyring, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with OpenPGP: %s", err)
+	}
+
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
@@ -0,0 +1,458 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	ageArmor "filippo.io/age/armor"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestAgeDecryptor(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	d, err := newAgeDecryptor(&AgeConfig{Identity: identity.String()}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	armored := testAgeEncrypt(t, identity.Recipient(), true, "MyStr0ngp@ss!")
+	binary := testAgeEncrypt(t, identity.Recipient(), false, "MyStr0ngp@ss!")
+
+	for _, ciphertext := range []string{armored, base64.StdEncoding.EncodeToString([]byte(binary))} {
+		plaintext, err := d.decrypt(ciphertext, CipherOptions{})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if string(plaintext) != "MyStr0ngp@ss!" {
+			t.Fatalf("expected decrypted value to match plaintext")
+		}
+	}
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := d.decrypt(testAgeEncrypt(t, other.Recipient(), true, "x"), CipherOptions{}); err == nil {
+		t.Fatalf("expected error decrypting for another recipient")
+	}
+}
+
+func TestAgeDecryptor_encryptedIdentity(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	d, err := newAgeDecryptor(&AgeConfig{EncryptedIdentity: kmsconn.testEncrypt(t, "alias/test", nil, identity.String())}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := d.decrypt(testAgeEncrypt(t, identity.Recipient(), true, "MyStr0ngp@ss!"), CipherOptions{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestPgpDecryptor(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+
+	// The default hash, RIPEMD-160, is not linked into the binary
+	config := &packet.Config{DefaultHash: crypto.SHA256}
+
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var privateKey bytes.Buffer
+	w, err := armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	w.Close()
+
+	d, err := newPgpDecryptor(&PgpConfig{PrivateKey: privateKey.String()}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var message bytes.Buffer
+	aw, err := armor.Encode(&message, "PGP MESSAGE", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	pw, err := openpgp.Encrypt(aw, []*openpgp.Entity{entity}, nil, nil, config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	io.WriteString(pw, "MyStr0ngp@ss!")
+	pw.Close()
+	aw.Close()
+
+	plaintext, err := d.decrypt(message.String(), CipherOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(plaintext) != "MyStr0ngp@ss!" {
+		t.Fatalf("expected decrypted value to match plaintext")
+	}
+}
+
+// The files in testdata/pgp were written by GnuPG 2.2: an RSA key without a
+// passphrase with a message encrypted to it, and a default Curve25519 key.
+func TestPgpDecryptor_gnupg(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+
+	d, err := newPgpDecryptor(&PgpConfig{PrivateKeyFile: filepath.Join("testdata", "pgp", "private_key.asc")}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	message, err := ioutil.ReadFile(filepath.Join("testdata", "pgp", "message.asc"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	plaintext, err := d.decrypt(string(message), CipherOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(plaintext) != "MyStr0ngp@ss!" {
+		t.Fatalf("expected decrypted value to match plaintext, got %q", plaintext)
+	}
+
+	_, err = newPgpDecryptor(&PgpConfig{PrivateKeyFile: filepath.Join("testdata", "pgp", "curve25519_private_key.asc")}, client)
+	if err == nil || !strings.Contains(err.Error(), "only RSA and ElGamal keys are supported") {
+		t.Fatalf("expected error reading a Curve25519 key, got %v", err)
+	}
+}
+
+// testAgeEncrypt encrypts plaintext to an age recipient.
+func testAgeEncrypt(t *testing.T, recipient age.Recipient, armored bool, plaintext string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopWriteCloser{&buf}
+	if armored {
+		out = ageArmor.NewWriter(&buf)
+	}
+
+	w, err := age.Encrypt(out, recipient)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	io.WriteString(w, plaintext)
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return buf.String()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// testVaultServer serves the Vault AppRole login and Transit decrypt API.
+// Logins issue a new token each time, which can be revoked.
+type testVaultServer struct {
+	*httptest.Server
+
+	leaseDuration int
+
+	mu       sync.Mutex
+	logins   int
+	requests int
+	tokens   map[string]bool
+}
+
+// testVaultCiphertexts are the ciphertexts of the Transit keys of the test
+// server and their plaintexts, the empty plaintext meaning none is returned.
+var testVaultCiphertexts = map[string]map[string]string{
+	"test":  {"vault:v1:c2VjcmV0": "MyStr0ngp@ss!", "vault:v1:ZW1wdHk=": ""},
+	"other": {"vault:v1:b3RoZXI=": "0ther"},
+}
+
+func newTestVaultServer(t *testing.T) *testVaultServer {
+	v := &testVaultServer{tokens: map[string]bool{"root": true}}
+	v.Server = httptest.NewServer(http.HandlerFunc(v.serveHTTP(t)))
+	t.Cleanup(v.Close)
+
+	return v
+}
+
+func (v *testVaultServer) serveHTTP(t *testing.T) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("err: %s", err)
+		}
+
+		if r.URL.Path == "/v1/auth/approle/login" {
+			if body["role_id"] != "role" || body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"errors":["invalid role or secret ID"]}`)
+				return
+			}
+
+			v.logins++
+			token := fmt.Sprintf("approle-token-%d", v.logins)
+			v.tokens[token] = true
+
+			fmt.Fprintf(w, `{"auth":{"client_token":%q,"lease_duration":%d}}`, token, v.leaseDuration)
+			return
+		}
+
+		ciphertexts, ok := testVaultCiphertexts[strings.TrimPrefix(r.URL.Path, "/v1/transit/decrypt/")]
+		if !strings.HasPrefix(r.URL.Path, "/v1/transit/decrypt/") || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"errors":[]}`)
+			return
+		}
+
+		v.requests++
+
+		if !v.tokens[r.Header.Get("X-Vault-Token")] {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+
+		plaintext, ok := ciphertexts[body["ciphertext"]]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":["invalid ciphertext: unable to decrypt"]}`)
+			return
+		}
+
+		if plaintext == "" {
+			fmt.Fprint(w, `{"data":{}}`)
+			return
+		}
+
+		fmt.Fprintf(w, `{"data":{"plaintext":%q}}`, base64.StdEncoding.EncodeToString([]byte(plaintext)))
+	}
+}
+
+// revokeTokens revokes every token issued by AppRole logins.
+func (v *testVaultServer) revokeTokens() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.tokens = map[string]bool{"root": true}
+}
+
+func TestVaultDecryptor(t *testing.T) {
+	server := newTestVaultServer(t)
+
+	testCases := []struct {
+		name        string
+		config      *VaultConfig
+		keyId       string
+		ciphertext  string
+		expected    string
+		expectError *regexp.Regexp
+	}{
+		{
+			name:       "token",
+			config:     &VaultConfig{Address: server.URL, Token: "root", Mount: "transit"},
+			keyId:      "test",
+			ciphertext: "vault:v1:c2VjcmV0",
+			expected:   "MyStr0ngp@ss!",
+		},
+		{
+			name:       "approle",
+			config:     &VaultConfig{Address: server.URL, AppRoleMount: "approle", AppRoleRoleId: "role", AppRoleSecretId: "secret", Mount: "transit"},
+			keyId:      "test",
+			ciphertext: "vault:v1:c2VjcmV0",
+			expected:   "MyStr0ngp@ss!",
+		},
+		{
+			name:       "resource key over key_name",
+			config:     &VaultConfig{Address: server.URL, Token: "root", Mount: "transit", KeyName: "test"},
+			keyId:      "other",
+			ciphertext: "vault:v1:b3RoZXI=",
+			expected:   "0ther",
+		},
+		{
+			name:       "key_name default",
+			config:     &VaultConfig{Address: server.URL, Token: "root", Mount: "transit", KeyName: "test"},
+			ciphertext: "vault:v1:c2VjcmV0",
+			expected:   "MyStr0ngp@ss!",
+		},
+		{
+			name:        "no key",
+			config:      &VaultConfig{Address: server.URL, Token: "root", Mount: "transit"},
+			ciphertext:  "vault:v1:c2VjcmV0",
+			expectError: regexp.MustCompile(`no key name, set encryption_key or the provider vault key_name`),
+		},
+		{
+			name:        "bad approle",
+			config:      &VaultConfig{Address: server.URL, AppRoleMount: "approle", AppRoleRoleId: "role", AppRoleSecretId: "wrong", Mount: "transit"},
+			keyId:       "test",
+			ciphertext:  "vault:v1:c2VjcmV0",
+			expectError: regexp.MustCompile(`invalid role or secret ID`),
+		},
+		{
+			name:        "bad token",
+			config:      &VaultConfig{Address: server.URL, Token: "revoked", Mount: "transit"},
+			keyId:       "test",
+			ciphertext:  "vault:v1:c2VjcmV0",
+			expectError: regexp.MustCompile(`\(test\): 403 Forbidden: permission denied`),
+		},
+		{
+			name:        "bad ciphertext",
+			config:      &VaultConfig{Address: server.URL, Token: "root", Mount: "transit"},
+			keyId:       "test",
+			ciphertext:  "vault:v1:b3RoZXI=",
+			expectError: regexp.MustCompile(`unable to decrypt`),
+		},
+		{
+			name:        "no plaintext",
+			config:      &VaultConfig{Address: server.URL, Token: "root", Mount: "transit"},
+			keyId:       "test",
+			ciphertext:  "vault:v1:ZW1wdHk=",
+			expectError: regexp.MustCompile(`\(test\): no plaintext returned`),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := newVaultDecryptor(tc.config)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			plaintext, err := d.decrypt(tc.ciphertext, CipherOptions{KeyId: tc.keyId})
+			if tc.expectError != nil {
+				if err == nil || !tc.expectError.MatchString(err.Error()) {
+					t.Fatalf("expected error matching %s, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if string(plaintext) != tc.expected {
+				t.Fatalf("expected decrypted value %q, got %q", tc.expected, plaintext)
+			}
+		})
+	}
+}
+
+func TestVaultDecryptor_appRoleLogin(t *testing.T) {
+	server := newTestVaultServer(t)
+	server.leaseDuration = 3600
+
+	d, err := newVaultDecryptor(&VaultConfig{Address: server.URL, AppRoleMount: "approle", AppRoleRoleId: "role", AppRoleSecretId: "secret", Mount: "transit"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	decrypt := func(expectedLogins, expectedRequests int) {
+		t.Helper()
+
+		plaintext, err := d.decrypt("vault:v1:c2VjcmV0", CipherOptions{KeyId: "test"})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if string(plaintext) != "MyStr0ngp@ss!" {
+			t.Fatalf("expected decrypted value to match plaintext")
+		}
+
+		if server.logins != expectedLogins || server.requests != expectedRequests {
+			t.Fatalf("expected %d logins and %d decrypt requests, got %d and %d", expectedLogins, expectedRequests, server.logins, server.requests)
+		}
+	}
+
+	// The token is reused until it expires
+	decrypt(1, 1)
+	decrypt(1, 2)
+
+	if remaining := time.Until(d.tokenExpiry); remaining <= 0 || remaining > time.Hour {
+		t.Fatalf("expected the token to be renewed within its lease, got %s", remaining)
+	}
+
+	d.tokenExpiry = time.Now().Add(-time.Second)
+	decrypt(2, 3)
+
+	// A revoked token is denied once, then replaced by logging in again
+	server.revokeTokens()
+	decrypt(3, 5)
+}
+
+func TestAWSClientDecrypt_cached(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	ciphertext := kmsconn.testEncrypt(t, "alias/test", map[string]string{"environment": "prod"}, "MyStr0ngp@ss!")
+	opts := CipherOptions{KeyId: "alias/test", EncryptionContext: map[string]string{"environment": "prod"}}
+
+	if _, err := client.decrypt(encryptionSchemeKms, ciphertext, opts); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := resolveKmsKeyArn("alias/test", client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// A KMS without the key only fails lookups that were not made before
+	client.kmsconn = newFakeKMS()
+
+	plaintext, err := client.decrypt(encryptionSchemeKms, ciphertext, opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if string(plaintext) != "MyStr0ngp@ss!" {
+		t.Fatalf("expected the cached plaintext, got %q", plaintext)
+	}
+
+	if _, err := resolveKmsKeyArn("alias/test", client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	opts.EncryptionContext = map[string]string{"environment": "dev"}
+	if _, err := client.decrypt(encryptionSchemeKms, ciphertext, opts); err == nil {
+		t.Fatalf("expected decrypting with another encryption context to fail")
+	}
+}
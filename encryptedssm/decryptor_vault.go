@@ -0,0 +1,220 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultConfig holds the HashiCorp Vault server the vault encryption_scheme
+// decrypts with, authenticating with a token or AppRole. KeyName is the
+// Transit key used when a value does not name one.
+type VaultConfig struct {
+	Address   string
+	Namespace string
+	Token     string
+
+	AppRoleMount    string
+	AppRoleRoleId   string
+	AppRoleSecretId string
+
+	Mount   string
+	KeyName string
+}
+
+// vaultDecryptor decrypts Vault Transit ciphertexts, of the form
+// vault:v1:..., through the Vault HTTP API.
+type vaultDecryptor struct {
+	config     *VaultConfig
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token string
+
+	// Time after which the AppRole token is renewed by logging in again,
+	// zero for tokens that do not expire.
+	tokenExpiry time.Time
+}
+
+// vaultResponse is the subset of Vault API responses used.
+type vaultResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// vaultError is the error of a Vault API request that did not succeed.
+type vaultError struct {
+	StatusCode int
+	Status     string
+	Errors     []string
+}
+
+func (e *vaultError) Error() string {
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("%s: %s", e.Status, strings.Join(e.Errors, ", "))
+	}
+
+	return e.Status
+}
+
+func newVaultDecryptor(config *VaultConfig) (*vaultDecryptor, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+
+	if config.Token == "" && (config.AppRoleRoleId == "" || config.AppRoleSecretId == "") {
+		return nil, fmt.Errorf("vault token or approle role_id and secret_id are required")
+	}
+
+	return &vaultDecryptor{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		token:      config.Token,
+	}, nil
+}
+
+// decrypt decrypts a ciphertext with the Transit key named by the KeyId of
+// opts, the encryption_key of the resource, or the configured key_name.
+func (v *vaultDecryptor) decrypt(ciphertext string, opts CipherOptions) ([]byte, error) {
+	keyName := opts.KeyId
+	if keyName == "" {
+		keyName = v.config.KeyName
+	}
+
+	if keyName == "" {
+		return nil, fmt.Errorf("error decrypting with Vault Transit: no key name, set encryption_key or the provider vault key_name")
+	}
+
+	path := fmt.Sprintf("%s/decrypt/%s", v.config.Mount, url.PathEscape(keyName))
+	body := map[string]string{"ciphertext": strings.TrimSpace(ciphertext)}
+
+	token, err := v.clientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.request(path, token, body)
+
+	// AppRole tokens can be revoked or expire before their lease ends, log in
+	// again once and retry
+	if vErr, ok := err.(*vaultError); ok && vErr.StatusCode == http.StatusForbidden && v.config.Token == "" {
+		log.Printf("[DEBUG] Vault denied the AppRole token, logging in again: %s", err)
+
+		v.invalidateToken(token)
+
+		if token, err = v.clientToken(); err != nil {
+			return nil, err
+		}
+
+		resp, err = v.request(path, token, body)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting with Vault Transit key (%s): %s", keyName, err)
+	}
+
+	plaintext, ok := resp.Data["plaintext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("error decrypting with Vault Transit key (%s): no plaintext returned", keyName)
+	}
+
+	return base64.StdEncoding.DecodeString(plaintext)
+}
+
+// clientToken returns the configured token, or logs in with AppRole the first
+// time it is called and again once the token has expired.
+func (v *vaultDecryptor) clientToken() (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.token != "" && (v.tokenExpiry.IsZero() || time.Now().Before(v.tokenExpiry)) {
+		return v.token, nil
+	}
+
+	path := fmt.Sprintf("auth/%s/login", v.config.AppRoleMount)
+
+	resp, err := v.request(path, "", map[string]string{
+		"role_id":   v.config.AppRoleRoleId,
+		"secret_id": v.config.AppRoleSecretId,
+	})
+	if err != nil {
+		return "", fmt.Errorf("error logging in to Vault with AppRole: %s", err)
+	}
+
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("error logging in to Vault with AppRole: no client token returned")
+	}
+
+	v.token = resp.Auth.ClientToken
+	v.tokenExpiry = time.Time{}
+
+	// Renew after 90% of the lease so requests are not made with a token
+	// about to expire
+	if resp.Auth.LeaseDuration > 0 {
+		v.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second * 9 / 10)
+	}
+
+	return v.token, nil
+}
+
+// invalidateToken discards the AppRole token so the next request logs in
+// again, unless another request already replaced it.
+func (v *vaultDecryptor) invalidateToken(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.token == token {
+		v.token = ""
+	}
+}
+
+// request makes a POST request to the Vault API.
+func (v *vaultDecryptor) request(path, token string, body interface{}) (*vaultResponse, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(v.config.Address, "/"), path), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	httpResp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp vaultResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil && httpResp.StatusCode < 300 {
+		return nil, fmt.Errorf("error decoding response: %s", err)
+	}
+
+	if httpResp.StatusCode >= 300 {
+		return nil, &vaultError{StatusCode: httpResp.StatusCode, Status: httpResp.Status, Errors: resp.Errors}
+	}
+
+	return &resp, nil
+}
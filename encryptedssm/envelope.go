@@ -0,0 +1,114 @@
+package encryptedssm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// envelope is the format accepted for values larger than the 4096 byte limit
+// of kms:Encrypt. The value is encrypted locally with AES-256-GCM under a data
+// key and only the data key is encrypted with KMS. The JSON document is base64
+// encoded before being set as encrypted_value.
+type envelope struct {
+	// KMS key the data key was encrypted with, informational only.
+	KeyId string `json:"key_id,omitempty"`
+
+	// Base64 encoded KMS ciphertext of the 256-bit data key.
+	EncryptedDataKey string `json:"encrypted_data_key"`
+
+	// Base64 encoded 12 byte AES-GCM nonce.
+	Nonce string `json:"nonce"`
+
+	// Base64 encoded AES-GCM ciphertext including the authentication tag.
+	Ciphertext string `json:"ciphertext"`
+}
+
+// parseEnvelope returns the envelope contained in a decoded encrypted_value.
+// KMS ciphertext blobs are binary and never decode as a JSON object, so false
+// is returned for them.
+func parseEnvelope(blob []byte) (*envelope, bool) {
+	if len(blob) == 0 || blob[0] != '{' {
+		return nil, false
+	}
+
+	var e envelope
+	if err := json.Unmarshal(blob, &e); err != nil || e.EncryptedDataKey == "" {
+		return nil, false
+	}
+
+	return &e, true
+}
+
+// encryptedDataKey returns the decoded KMS ciphertext of the data key.
+func (e *envelope) encryptedDataKey() ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(e.EncryptedDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding envelope encrypted_data_key: %w", err)
+	}
+
+	return blob, nil
+}
+
+// open decrypts the envelope payload with the plaintext data key.
+func (e *envelope) open(dataKey []byte) ([]byte, error) {
+	nonce, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding envelope nonce: %w", err)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding envelope ciphertext: %w", err)
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating envelope cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating envelope cipher: %w", err)
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("envelope nonce must be %d bytes, got %d", gcm.NonceSize(), len(nonce))
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting envelope: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// sealEnvelope encrypts plaintext under a data key generated with
+// kms:GenerateDataKey and returns the JSON encoded envelope.
+func sealEnvelope(keyId string, dataKey []byte, encryptedDataKey []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error creating envelope cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating envelope cipher: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating envelope nonce: %w", err)
+	}
+
+	return json.Marshal(&envelope{
+		KeyId:            keyId,
+		EncryptedDataKey: base64.StdEncoding.EncodeToString(encryptedDataKey),
+		Nonce:            base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext:       base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil)),
+	})
+}
@@ -0,0 +1,59 @@
+package encryptedssm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope_roundTrip(t *testing.T) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	plaintext := []byte(strings.Repeat("x", 8192))
+
+	blob, err := sealEnvelope("arn:aws:kms:us-east-1:123456789012:key/test", dataKey, []byte{0x01, 0x02}, plaintext)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	env, ok := parseEnvelope(blob)
+	if !ok {
+		t.Fatalf("expected sealed envelope to parse")
+	}
+
+	encryptedDataKey, err := env.encryptedDataKey()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !bytes.Equal(encryptedDataKey, []byte{0x01, 0x02}) {
+		t.Fatalf("unexpected encrypted data key: %s", base64.StdEncoding.EncodeToString(encryptedDataKey))
+	}
+
+	opened, err := env.open(dataKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("expected opened envelope to match plaintext")
+	}
+
+	dataKey[0] ^= 0xff
+	if _, err := env.open(dataKey); err == nil {
+		t.Fatalf("expected error opening envelope with the wrong data key")
+	}
+}
+
+func TestParseEnvelope_kmsCiphertext(t *testing.T) {
+	for _, blob := range [][]byte{nil, {0x01, 0x02, 0x02, 0x00}, []byte(`{"nonce":"AAAA"}`)} {
+		if _, ok := parseEnvelope(blob); ok {
+			t.Errorf("expected %q not to parse as an envelope", blob)
+		}
+	}
+}
@@ -0,0 +1,843 @@
+package encryptedssm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	testAccountId = "123456789012"
+	testRegion    = "us-east-1"
+)
+
+// newTestAWSClient returns an AWSClient backed by in-memory SSM, KMS and
+// Secrets Manager fakes with a single KMS key, aliased as alias/test.
+func newTestAWSClient() (*AWSClient, *fakeSSM, *fakeKMS) {
+	ssmconn := newFakeSSM()
+	kmsconn := newFakeKMS("alias/test")
+
+	return &AWSClient{ssmconn: ssmconn, kmsconn: kmsconn, secretsmanagerconn: newFakeSecretsManager()}, ssmconn, kmsconn
+}
+
+// testAccProviderFactories returns provider factories whose provider is
+// configured with the given client rather than real AWS sessions.
+func testAccProviderFactories(client *AWSClient) map[string]func() (*schema.Provider, error) {
+	return map[string]func() (*schema.Provider, error){
+		"encryptedssm": func() (*schema.Provider, error) {
+			p := Provider()
+			p.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
+				config := Config{
+					AgeConfig:   expandProviderAge(d.Get("age").([]interface{})),
+					PgpConfig:   expandProviderPgp(d.Get("pgp").([]interface{})),
+					VaultConfig: expandProviderVault(d.Get("vault").([]interface{})),
+				}
+
+				if err := config.configureDecryptors(client); err != nil {
+					return nil, err
+				}
+
+				client.DefaultTagsConfig = expandProviderDefaultTags(d.Get("default_tags").([]interface{}))
+				client.IgnoreTagsConfig = expandProviderIgnoreTags(d.Get("ignore_tags").([]interface{}))
+				return client, nil
+			}
+			return p, nil
+		},
+	}
+}
+
+type fakeSSMParameter struct {
+	Name           string
+	Type           string
+	Value          string
+	KeyId          string
+	Tier           string
+	DataType       string
+	Description    string
+	AllowedPattern string
+	Version        int64
+	Tags           map[string]string
+}
+
+// fakeSSM is an in-memory implementation of the SSM operations used by the
+// provider. Operations not implemented panic through the nil SSMAPI.
+type fakeSSM struct {
+	ssmiface.SSMAPI
+
+	mu         sync.Mutex
+	parameters map[string]*fakeSSMParameter
+}
+
+func newFakeSSM() *fakeSSM {
+	return &fakeSSM{parameters: make(map[string]*fakeSSMParameter)}
+}
+
+// parameter returns a copy of the named parameter, or nil.
+func (c *fakeSSM) parameter(name string) *fakeSSMParameter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[name]
+	if !ok {
+		return nil
+	}
+
+	copied := *p
+	copied.Tags = make(map[string]string, len(p.Tags))
+	for k, v := range p.Tags {
+		copied.Tags[k] = v
+	}
+
+	return &copied
+}
+
+// update modifies a parameter outside of Terraform.
+func (c *fakeSSM) update(name string, f func(*fakeSSMParameter)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f(c.parameters[name])
+}
+
+func (c *fakeSSM) arn(name string) string {
+	return fmt.Sprintf("arn:aws:ssm:%s:%s:parameter/%s", testRegion, testAccountId, strings.TrimPrefix(name, "/"))
+}
+
+func (c *fakeSSM) PutParameter(input *ssm.PutParameterInput) (*ssm.PutParameterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	p, exists := c.parameters[name]
+
+	if exists && !aws.BoolValue(input.Overwrite) {
+		return nil, awserr.New(ssm.ErrCodeParameterAlreadyExists, "The parameter already exists.", nil)
+	}
+
+	tier := aws.StringValue(input.Tier)
+	if tier == "" {
+		tier = ssm.ParameterTierStandard
+	}
+
+	if input.KeyId != nil && aws.StringValue(input.Type) != ssm.ParameterTypeSecureString {
+		return nil, awserr.New("ValidationException", "KeyId is required for SecureString type parameter only.", nil)
+	}
+
+	if exists && p.Tier == ssm.ParameterTierAdvanced && tier == ssm.ParameterTierStandard {
+		return nil, awserr.New("ValidationException", "This parameter uses the advanced-parameter tier. You can't downgrade a parameter from the advanced-parameter tier to the standard-parameter tier.", nil)
+	}
+
+	if !exists {
+		p = &fakeSSMParameter{Name: name, Tags: make(map[string]string)}
+		for _, tag := range input.Tags {
+			p.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		c.parameters[name] = p
+	}
+
+	p.Type = aws.StringValue(input.Type)
+	p.Value = aws.StringValue(input.Value)
+	p.Tier = tier
+	p.AllowedPattern = aws.StringValue(input.AllowedPattern)
+	p.DataType = aws.StringValue(input.DataType)
+	if p.DataType == "" {
+		p.DataType = "text"
+	}
+	if input.Description != nil {
+		p.Description = aws.StringValue(input.Description)
+	}
+
+	p.KeyId = ""
+	if p.Type == ssm.ParameterTypeSecureString {
+		p.KeyId = aws.StringValue(input.KeyId)
+		if p.KeyId == "" {
+			p.KeyId = "alias/aws/ssm"
+		}
+	}
+
+	p.Version++
+
+	return &ssm.PutParameterOutput{Tier: aws.String(p.Tier), Version: aws.Int64(p.Version)}, nil
+}
+
+func (c *fakeSSM) GetParameter(input *ssm.GetParameterInput) (*ssm.GetParameterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[aws.StringValue(input.Name)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "", nil)
+	}
+
+	return &ssm.GetParameterOutput{
+		Parameter: &ssm.Parameter{
+			ARN:      aws.String(c.arn(p.Name)),
+			DataType: aws.String(p.DataType),
+			Name:     aws.String(p.Name),
+			Type:     aws.String(p.Type),
+			Value:    aws.String(p.Value),
+			Version:  aws.Int64(p.Version),
+		},
+	}, nil
+}
+
+func (c *fakeSSM) GetParametersByPathPages(input *ssm.GetParametersByPathInput, fn func(*ssm.GetParametersByPathOutput, bool) bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := strings.TrimSuffix(aws.StringValue(input.Path), "/") + "/"
+
+	var names []string
+	for name := range c.parameters {
+		if !strings.HasPrefix(name, path) {
+			continue
+		}
+		if !aws.BoolValue(input.Recursive) && strings.Contains(strings.TrimPrefix(name, path), "/") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// One parameter per page to exercise pagination
+	for i, name := range names {
+		p := c.parameters[name]
+		page := &ssm.GetParametersByPathOutput{
+			Parameters: []*ssm.Parameter{{
+				ARN:     aws.String(c.arn(p.Name)),
+				Name:    aws.String(p.Name),
+				Type:    aws.String(p.Type),
+				Value:   aws.String(p.Value),
+				Version: aws.Int64(p.Version),
+			}},
+		}
+		if !fn(page, i == len(names)-1) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *fakeSSM) DescribeParameters(input *ssm.DescribeParametersInput) (*ssm.DescribeParametersOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output := &ssm.DescribeParametersOutput{}
+
+	for _, filter := range input.ParameterFilters {
+		if aws.StringValue(filter.Key) != "Name" {
+			continue
+		}
+
+		for _, name := range filter.Values {
+			p, ok := c.parameters[aws.StringValue(name)]
+			if !ok {
+				continue
+			}
+
+			metadata := &ssm.ParameterMetadata{
+				DataType: aws.String(p.DataType),
+				Name:     aws.String(p.Name),
+				Tier:     aws.String(p.Tier),
+				Type:     aws.String(p.Type),
+				Version:  aws.Int64(p.Version),
+			}
+			if p.KeyId != "" {
+				metadata.KeyId = aws.String(p.KeyId)
+			}
+			if p.Description != "" {
+				metadata.Description = aws.String(p.Description)
+			}
+			if p.AllowedPattern != "" {
+				metadata.AllowedPattern = aws.String(p.AllowedPattern)
+			}
+
+			output.Parameters = append(output.Parameters, metadata)
+		}
+	}
+
+	return output, nil
+}
+
+func (c *fakeSSM) DeleteParameter(input *ssm.DeleteParameterInput) (*ssm.DeleteParameterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	if _, ok := c.parameters[name]; !ok {
+		return nil, awserr.New(ssm.ErrCodeParameterNotFound, "", nil)
+	}
+
+	delete(c.parameters, name)
+
+	return &ssm.DeleteParameterOutput{}, nil
+}
+
+func (c *fakeSSM) ListTagsForResource(input *ssm.ListTagsForResourceInput) (*ssm.ListTagsForResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[aws.StringValue(input.ResourceId)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	output := &ssm.ListTagsForResourceOutput{}
+	for k, v := range p.Tags {
+		output.TagList = append(output.TagList, &ssm.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	return output, nil
+}
+
+func (c *fakeSSM) AddTagsToResource(input *ssm.AddTagsToResourceInput) (*ssm.AddTagsToResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[aws.StringValue(input.ResourceId)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	for _, tag := range input.Tags {
+		p.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &ssm.AddTagsToResourceOutput{}, nil
+}
+
+func (c *fakeSSM) RemoveTagsFromResource(input *ssm.RemoveTagsFromResourceInput) (*ssm.RemoveTagsFromResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.parameters[aws.StringValue(input.ResourceId)]
+	if !ok {
+		return nil, awserr.New(ssm.ErrCodeInvalidResourceId, "", nil)
+	}
+
+	for _, k := range input.TagKeys {
+		delete(p.Tags, aws.StringValue(k))
+	}
+
+	return &ssm.RemoveTagsFromResourceOutput{}, nil
+}
+
+type fakeSecretsManagerSecret struct {
+	ARN         string
+	Name        string
+	Description string
+	KmsKeyId    string
+	Deleted     bool
+	Value       string
+	VersionId   string
+	Versions    int
+	Tags        map[string]string
+}
+
+// fakeSecretsManager is an in-memory implementation of the Secrets Manager
+// operations used by the provider. Only the current version of each secret
+// is kept.
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+
+	mu      sync.Mutex
+	secrets map[string]*fakeSecretsManagerSecret
+}
+
+func newFakeSecretsManager() *fakeSecretsManager {
+	return &fakeSecretsManager{secrets: make(map[string]*fakeSecretsManagerSecret)}
+}
+
+// secret returns the secret with the given name or ARN, callers must hold mu.
+func (c *fakeSecretsManager) secret(secretId *string) (*fakeSecretsManagerSecret, error) {
+	for _, s := range c.secrets {
+		if s.Name == aws.StringValue(secretId) || s.ARN == aws.StringValue(secretId) {
+			return s, nil
+		}
+	}
+
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+}
+
+// lookup returns a copy of the named secret, or nil.
+func (c *fakeSecretsManager) lookup(name string) *fakeSecretsManagerSecret {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.secrets[name]
+	if !ok {
+		return nil
+	}
+
+	copied := *s
+	copied.Tags = make(map[string]string, len(s.Tags))
+	for k, v := range s.Tags {
+		copied.Tags[k] = v
+	}
+
+	return &copied
+}
+
+// update modifies a secret outside of Terraform.
+func (c *fakeSecretsManager) update(name string, f func(*fakeSecretsManagerSecret)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f(c.secrets[name])
+}
+
+func (c *fakeSecretsManager) CreateSecret(input *secretsmanager.CreateSecretInput) (*secretsmanager.CreateSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := aws.StringValue(input.Name)
+	if _, ok := c.secrets[name]; ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceExistsException, "The operation failed because the secret already exists.", nil)
+	}
+
+	s := &fakeSecretsManagerSecret{
+		ARN:         fmt.Sprintf("arn:aws:secretsmanager:%s:%s:secret:%s-AbCdEf", testRegion, testAccountId, name),
+		Name:        name,
+		Description: aws.StringValue(input.Description),
+		KmsKeyId:    aws.StringValue(input.KmsKeyId),
+		Tags:        make(map[string]string),
+	}
+	for _, tag := range input.Tags {
+		s.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	c.secrets[name] = s
+
+	return &secretsmanager.CreateSecretOutput{ARN: aws.String(s.ARN), Name: aws.String(s.Name)}, nil
+}
+
+func (c *fakeSecretsManager) DescribeSecret(input *secretsmanager.DescribeSecretInput) (*secretsmanager.DescribeSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &secretsmanager.DescribeSecretOutput{
+		ARN:  aws.String(s.ARN),
+		Name: aws.String(s.Name),
+	}
+	if s.Description != "" {
+		output.Description = aws.String(s.Description)
+	}
+	if s.KmsKeyId != "" {
+		output.KmsKeyId = aws.String(s.KmsKeyId)
+	}
+	if s.Deleted {
+		output.DeletedDate = aws.Time(time.Now())
+	}
+	for k, v := range s.Tags {
+		output.Tags = append(output.Tags, &secretsmanager.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	return output, nil
+}
+
+func (c *fakeSecretsManager) UpdateSecret(input *secretsmanager.UpdateSecretInput) (*secretsmanager.UpdateSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Description != nil {
+		s.Description = aws.StringValue(input.Description)
+	}
+	if input.KmsKeyId != nil {
+		s.KmsKeyId = aws.StringValue(input.KmsKeyId)
+	}
+
+	return &secretsmanager.UpdateSecretOutput{ARN: aws.String(s.ARN), Name: aws.String(s.Name)}, nil
+}
+
+func (c *fakeSecretsManager) DeleteSecret(input *secretsmanager.DeleteSecretInput) (*secretsmanager.DeleteSecretOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(input.ForceDeleteWithoutRecovery) {
+		delete(c.secrets, s.Name)
+	} else {
+		s.Deleted = true
+	}
+
+	return &secretsmanager.DeleteSecretOutput{ARN: aws.String(s.ARN), Name: aws.String(s.Name)}, nil
+}
+
+func (c *fakeSecretsManager) PutSecretValue(input *secretsmanager.PutSecretValueInput) (*secretsmanager.PutSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Deleted {
+		return nil, awserr.New(secretsmanager.ErrCodeInvalidRequestException, "You can't perform this operation on the secret because it was marked for deletion.", nil)
+	}
+
+	s.Value = aws.StringValue(input.SecretString)
+	s.Versions++
+	s.VersionId = fmt.Sprintf("00000000-0000-0000-0000-%012d", s.Versions)
+
+	return &secretsmanager.PutSecretValueOutput{
+		ARN:       aws.String(s.ARN),
+		Name:      aws.String(s.Name),
+		VersionId: aws.String(s.VersionId),
+	}, nil
+}
+
+func (c *fakeSecretsManager) GetSecretValue(input *secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Deleted {
+		return nil, awserr.New(secretsmanager.ErrCodeInvalidRequestException, "You can't perform this operation on the secret because it was marked for deletion.", nil)
+	}
+
+	if s.VersionId == "" {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret value for staging label: AWSCURRENT", nil)
+	}
+
+	return &secretsmanager.GetSecretValueOutput{
+		ARN:          aws.String(s.ARN),
+		Name:         aws.String(s.Name),
+		SecretString: aws.String(s.Value),
+		VersionId:    aws.String(s.VersionId),
+	}, nil
+}
+
+func (c *fakeSecretsManager) TagResource(input *secretsmanager.TagResourceInput) (*secretsmanager.TagResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range input.Tags {
+		s.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	return &secretsmanager.TagResourceOutput{}, nil
+}
+
+func (c *fakeSecretsManager) UntagResource(input *secretsmanager.UntagResourceInput) (*secretsmanager.UntagResourceOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, err := c.secret(input.SecretId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range input.TagKeys {
+		delete(s.Tags, aws.StringValue(k))
+	}
+
+	return &secretsmanager.UntagResourceOutput{}, nil
+}
+
+// fakeCiphertext is the content of fakeKMS ciphertext blobs. Blobs are
+// prefixed with a version byte so they never parse as an envelope.
+type fakeCiphertext struct {
+	KeyArn    string
+	Algorithm string
+	Context   map[string]string
+	Nonce     []byte
+	Plaintext []byte
+}
+
+// fakeKMSKey is a key of fakeKMS.
+type fakeKMSKey struct {
+	Arn     string
+	KeySpec string
+
+	// DER public key of asymmetric keys
+	PublicKey []byte
+}
+
+func (k *fakeKMSKey) asymmetric() bool {
+	return k.KeySpec != kms.CustomerMasterKeySpecSymmetricDefault
+}
+
+// fakeKMS is an in-memory implementation of the KMS operations used by the
+// provider. Ciphertexts are not actually encrypted, but are bound to their
+// key, algorithm and encryption context. As with KMS, encrypting the same
+// plaintext twice returns different ciphertexts.
+type fakeKMS struct {
+	kmsiface.KMSAPI
+
+	// Keys by key ID, ARN and alias name
+	keys map[string]*fakeKMSKey
+}
+
+// newFakeKMS returns a fakeKMS with a single symmetric key with the given
+// aliases.
+func newFakeKMS(aliases ...string) *fakeKMS {
+	c := &fakeKMS{keys: make(map[string]*fakeKMSKey)}
+	c.addKey("1234abcd-12ab-34cd-56ef-1234567890ab", kms.CustomerMasterKeySpecSymmetricDefault, aliases...)
+
+	return c
+}
+
+// addKey adds a key with the given key spec and aliases, returning its ARN.
+// RSA keys are generated with a new key pair.
+func (c *fakeKMS) addKey(keyId, keySpec string, aliases ...string) string {
+	key := &fakeKMSKey{
+		Arn:     fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", testRegion, testAccountId, keyId),
+		KeySpec: keySpec,
+	}
+
+	if strings.HasPrefix(keySpec, "RSA_") {
+		bits, _ := strconv.Atoi(strings.TrimPrefix(keySpec, "RSA_"))
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			panic(err)
+		}
+
+		if key.PublicKey, err = x509.MarshalPKIXPublicKey(&privateKey.PublicKey); err != nil {
+			panic(err)
+		}
+	}
+
+	c.keys[keyId] = key
+	c.keys[key.Arn] = key
+	for _, alias := range aliases {
+		c.keys[alias] = key
+	}
+
+	return key.Arn
+}
+
+func (c *fakeKMS) key(keyId *string) (*fakeKMSKey, error) {
+	key, ok := c.keys[aws.StringValue(keyId)]
+	if !ok {
+		return nil, awserr.New(kms.ErrCodeNotFoundException, fmt.Sprintf("Key '%s' does not exist", aws.StringValue(keyId)), nil)
+	}
+
+	return key, nil
+}
+
+func (c *fakeKMS) keyArn(keyId *string) (string, error) {
+	key, err := c.key(keyId)
+	if err != nil {
+		return "", err
+	}
+
+	return key.Arn, nil
+}
+
+func (c *fakeKMS) encrypt(keyArn, algorithm string, context map[string]*string, plaintext []byte) []byte {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+
+	blob, _ := json.Marshal(&fakeCiphertext{
+		KeyArn:    keyArn,
+		Algorithm: algorithm,
+		Context:   aws.StringValueMap(context),
+		Nonce:     nonce,
+		Plaintext: plaintext,
+	})
+
+	return append([]byte{0x01}, blob...)
+}
+
+// testEncrypt returns plaintext encrypted under keyId in the base64 format
+// accepted by encrypted_value.
+func (c *fakeKMS) testEncrypt(t *testing.T, keyId string, context map[string]string, plaintext string) string {
+	t.Helper()
+
+	output, err := c.Encrypt(&kms.EncryptInput{
+		KeyId:             aws.String(keyId),
+		EncryptionContext: aws.StringMap(context),
+		Plaintext:         []byte(plaintext),
+	})
+	if err != nil {
+		t.Fatalf("error encrypting: %s", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(output.CiphertextBlob)
+}
+
+// decrypt returns the plaintext of a value in the base64 format accepted by
+// encrypted_value.
+func (c *fakeKMS) decrypt(encryptedValue string) (string, error) {
+	blob, err := base64.StdEncoding.DecodeString(encryptedValue)
+	if err != nil {
+		return "", err
+	}
+
+	var ciphertext fakeCiphertext
+	if len(blob) == 0 || blob[0] != 0x01 || json.Unmarshal(blob[1:], &ciphertext) != nil {
+		return "", fmt.Errorf("not a fake KMS ciphertext")
+	}
+
+	return string(ciphertext.Plaintext), nil
+}
+
+func (c *fakeKMS) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	key, err := c.key(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := aws.StringValue(input.EncryptionAlgorithm)
+	if algorithm == "" {
+		algorithm = kms.EncryptionAlgorithmSpecSymmetricDefault
+	}
+
+	if key.asymmetric() != (algorithm != kms.EncryptionAlgorithmSpecSymmetricDefault) {
+		return nil, awserr.New(kms.ErrCodeInvalidKeyUsageException, fmt.Sprintf("%s is not a valid encryption algorithm for key %s", algorithm, key.Arn), nil)
+	}
+
+	return &kms.EncryptOutput{
+		CiphertextBlob:      c.encrypt(key.Arn, algorithm, input.EncryptionContext, input.Plaintext),
+		EncryptionAlgorithm: aws.String(algorithm),
+		KeyId:               aws.String(key.Arn),
+	}, nil
+}
+
+func (c *fakeKMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	var ciphertext fakeCiphertext
+	if len(input.CiphertextBlob) == 0 || input.CiphertextBlob[0] != 0x01 || json.Unmarshal(input.CiphertextBlob[1:], &ciphertext) != nil {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "", nil)
+	}
+
+	algorithm := aws.StringValue(input.EncryptionAlgorithm)
+	if algorithm == "" {
+		algorithm = kms.EncryptionAlgorithmSpecSymmetricDefault
+	}
+
+	if input.KeyId != nil {
+		keyArn, err := c.keyArn(input.KeyId)
+		if err != nil {
+			return nil, err
+		}
+
+		if keyArn != ciphertext.KeyArn {
+			return nil, awserr.New(kms.ErrCodeIncorrectKeyException, "The key ID in the request does not identify a CMK that can perform this operation.", nil)
+		}
+	} else if algorithm != kms.EncryptionAlgorithmSpecSymmetricDefault {
+		return nil, awserr.New("ValidationException", "KeyId is required for asymmetric keys", nil)
+	}
+
+	if ciphertext.Algorithm != "" && algorithm != ciphertext.Algorithm {
+		return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "", nil)
+	}
+
+	context := aws.StringValueMap(input.EncryptionContext)
+	if len(context) != 0 || len(ciphertext.Context) != 0 {
+		if !reflect.DeepEqual(context, ciphertext.Context) {
+			return nil, awserr.New(kms.ErrCodeInvalidCiphertextException, "", nil)
+		}
+	}
+
+	return &kms.DecryptOutput{
+		EncryptionAlgorithm: aws.String(algorithm),
+		KeyId:               aws.String(ciphertext.KeyArn),
+		Plaintext:           ciphertext.Plaintext,
+	}, nil
+}
+
+func (c *fakeKMS) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	key, err := c.key(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.asymmetric() {
+		return nil, awserr.New(kms.ErrCodeInvalidKeyUsageException, fmt.Sprintf("%s key usage is ENCRYPT_DECRYPT which is not valid for GenerateDataKey", key.Arn), nil)
+	}
+
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+
+	return &kms.GenerateDataKeyOutput{
+		CiphertextBlob: c.encrypt(key.Arn, kms.EncryptionAlgorithmSpecSymmetricDefault, input.EncryptionContext, plaintext),
+		KeyId:          aws.String(key.Arn),
+		Plaintext:      plaintext,
+	}, nil
+}
+
+func (c *fakeKMS) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	key, err := c.key(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kms.DescribeKeyOutput{
+		KeyMetadata: &kms.KeyMetadata{
+			Arn:                   aws.String(key.Arn),
+			CustomerMasterKeySpec: aws.String(key.KeySpec),
+			KeyId:                 aws.String(key.Arn[strings.LastIndex(key.Arn, "/")+1:]),
+			KeyUsage:              aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		},
+	}, nil
+}
+
+func (c *fakeKMS) GetPublicKey(input *kms.GetPublicKeyInput) (*kms.GetPublicKeyOutput, error) {
+	key, err := c.key(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !key.asymmetric() {
+		return nil, awserr.New(kms.ErrCodeUnsupportedOperationException, fmt.Sprintf("%s key usage is ENCRYPT_DECRYPT which is not valid for GetPublicKey", key.Arn), nil)
+	}
+
+	return &kms.GetPublicKeyOutput{
+		CustomerMasterKeySpec: aws.String(key.KeySpec),
+		EncryptionAlgorithms: aws.StringSlice([]string{
+			kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+			kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+		}),
+		KeyId:     aws.String(key.Arn),
+		KeyUsage:  aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		PublicKey: key.PublicKey,
+	}, nil
+}
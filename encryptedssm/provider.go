@@ -2,6 +2,7 @@ package encryptedssm
 
 import (
 	"log"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -66,6 +67,41 @@ func Provider() *schema.Provider {
 				Description: descriptions["max_retries"],
 			},
 
+			"insecure": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["insecure"],
+			},
+
+			"skip_credentials_validation": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["skip_credentials_validation"],
+			},
+
+			"skip_region_validation": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["skip_region_validation"],
+			},
+
+			"skip_requesting_account_id": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["skip_requesting_account_id"],
+			},
+
+			"skip_metadata_api_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: descriptions["skip_metadata_api_check"],
+			},
+
 			"allowed_account_ids": {
 				Type:          schema.TypeSet,
 				Elem:          &schema.Schema{Type: schema.TypeString},
@@ -82,12 +118,191 @@ func Provider() *schema.Provider {
 				Set:           schema.HashString,
 			},
 
+			"age": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Configuration block with the identity to decrypt the age encryption_scheme with.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"identity": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "age identity file contents, such as AGE-SECRET-KEY-1....",
+						},
+						"identity_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path of an age identity file.",
+						},
+						"encrypted_identity": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "age identity file contents encrypted with KMS, as base64.",
+						},
+					},
+				},
+			},
+
+			"default_tags": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Configuration block with settings to default resource tags across all resources.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"tags": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Description: "Resource tags to default across all resources.",
+						},
+					},
+				},
+			},
+
 			"endpoints": endpointsSchema(),
+
+			"pgp": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Configuration block with the private key to decrypt the pgp encryption_scheme with.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"private_key": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Armored OpenPGP private key.",
+						},
+						"private_key_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Path of an armored or binary OpenPGP private key.",
+						},
+						"encrypted_private_key": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Armored OpenPGP private key encrypted with KMS, as base64.",
+						},
+						"passphrase": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Passphrase the private key is protected with.",
+						},
+					},
+				},
+			},
+
+			"vault": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Configuration block with the Vault Transit key to decrypt the vault encryption_scheme with.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"address": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							DefaultFunc: schema.EnvDefaultFunc("VAULT_ADDR", nil),
+							Description: "URL of the Vault server.",
+						},
+						"namespace": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							DefaultFunc: schema.EnvDefaultFunc("VAULT_NAMESPACE", nil),
+							Description: "Vault Enterprise namespace.",
+						},
+						"token": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							DefaultFunc: schema.EnvDefaultFunc("VAULT_TOKEN", nil),
+							Description: "Vault token, in place of approle.",
+						},
+						"approle": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"mount": {
+										Type:        schema.TypeString,
+										Optional:    true,
+										Default:     "approle",
+										Description: "Path the AppRole auth method is mounted at.",
+									},
+									"role_id": {
+										Type:        schema.TypeString,
+										Required:    true,
+										Description: "AppRole role ID.",
+									},
+									"secret_id": {
+										Type:        schema.TypeString,
+										Required:    true,
+										Sensitive:   true,
+										Description: "AppRole secret ID.",
+									},
+								},
+							},
+						},
+						"mount": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     "transit",
+							Description: "Path the Transit secrets engine is mounted at.",
+						},
+						"key_name": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "Name of the Transit key values are decrypted with when they do not name one.",
+						},
+					},
+				},
+			},
+
+			"ignore_tags": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Configuration block with settings to ignore resource tags across all resources.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"keys": {
+							Type:        schema.TypeSet,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Set:         schema.HashString,
+							Description: "Resource tag keys to ignore across all resources.",
+						},
+						"key_prefixes": {
+							Type:        schema.TypeSet,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Set:         schema.HashString,
+							Description: "Resource tag key prefixes to ignore across all resources.",
+						},
+					},
+				},
+			},
 		},
-		ResourcesMap: map[string]*schema.Resource{
-			"encryptedssm_parameter": resourceAwsSsmParameter(),
+		DataSourcesMap: map[string]*schema.Resource{
+			"encryptedssm_kms_public_key":     dataSourceAwsKmsPublicKey(),
+			"encryptedssm_parameter":          dataSourceAwsSsmParameter(),
+			"encryptedssm_parameters_by_path": dataSourceAwsSsmParametersByPath(),
+			"encryptedssm_sops_file":          dataSourceAwsSopsFile(),
 		},
 	}
+	// Resources decrypt ciphertexts while diffing, which only receives the
+	// configured client through the provider
+	provider.ResourcesMap = map[string]*schema.Resource{
+		"encryptedssm_parameter":                     resourceAwsSsmParameter(provider.Meta),
+		"encryptedssm_secretsmanager_secret":         resourceAwsSecretsManagerSecret(),
+		"encryptedssm_secretsmanager_secret_version": resourceAwsSecretsManagerSecretVersion(provider.Meta),
+	}
 	provider.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
 		terraformVersion := provider.TerraformVersion
 		if terraformVersion == "" {
@@ -128,22 +343,57 @@ func init() {
 			"thrown.",
 
 		"endpoint": "Use this to override the default service endpoint URL",
+
+		"insecure": "Explicitly allow the provider to perform \"insecure\" SSL requests. If omitted, " +
+			"default value is `false`",
+
+		"skip_credentials_validation": "Skip the credentials validation via STS API. " +
+			"Used for AWS API implementations that do not have STS available/implemented.",
+
+		"skip_region_validation": "Skip static validation of region name. " +
+			"Used by users of alternative AWS-like APIs or users w/ access to regions that are not public (yet).",
+
+		"skip_requesting_account_id": "Skip requesting the account ID. " +
+			"Used for AWS API implementations that do not have IAM/STS API and/or metadata API.",
+
+		"skip_metadata_api_check": "Skip the AWS Metadata API check. " +
+			"Used for AWS API implementations that do not have a metadata api endpoint.",
 	}
 	endpointServiceNames = []string{
+		"iam",
+		"kms",
+		"secretsmanager",
 		"ssm",
+		"sts",
 	}
 }
 
 func providerConfigure(d *schema.ResourceData, terraformVersion string) (interface{}, error) {
-	config := Config{
-		AccessKey:        d.Get("access_key").(string),
-		SecretKey:        d.Get("secret_key").(string),
-		Profile:          d.Get("profile").(string),
-		Token:            d.Get("token").(string),
-		Region:           d.Get("region").(string),
-		CredsFilename:    d.Get("shared_credentials_file").(string),
-		MaxRetries:       d.Get("max_retries").(int),
-		terraformVersion: terraformVersion,
+	return expandProviderConfig(d, terraformVersion).Client()
+}
+
+// expandProviderConfig builds the client Config from the provider arguments.
+func expandProviderConfig(d *schema.ResourceData, terraformVersion string) *Config {
+	config := &Config{
+		AccessKey:         d.Get("access_key").(string),
+		SecretKey:         d.Get("secret_key").(string),
+		Profile:           d.Get("profile").(string),
+		Token:             d.Get("token").(string),
+		Region:            d.Get("region").(string),
+		CredsFilename:     d.Get("shared_credentials_file").(string),
+		MaxRetries:        d.Get("max_retries").(int),
+		Insecure:          d.Get("insecure").(bool),
+		AgeConfig:         expandProviderAge(d.Get("age").([]interface{})),
+		DefaultTagsConfig: expandProviderDefaultTags(d.Get("default_tags").([]interface{})),
+		IgnoreTagsConfig:  expandProviderIgnoreTags(d.Get("ignore_tags").([]interface{})),
+		PgpConfig:         expandProviderPgp(d.Get("pgp").([]interface{})),
+		VaultConfig:       expandProviderVault(d.Get("vault").([]interface{})),
+		terraformVersion:  terraformVersion,
+
+		SkipCredsValidation:     d.Get("skip_credentials_validation").(bool),
+		SkipMetadataApiCheck:    d.Get("skip_metadata_api_check").(bool),
+		SkipRegionValidation:    d.Get("skip_region_validation").(bool),
+		SkipRequestingAccountId: d.Get("skip_requesting_account_id").(bool),
 	}
 
 	if l, ok := d.Get("assume_role").([]interface{}); ok && len(l) > 0 && l[0] != nil {
@@ -210,6 +460,19 @@ func providerConfigure(d *schema.ResourceData, terraformVersion string) (interfa
 		log.Printf("[INFO] assume_role configuration set: (ARN: %q, SessionID: %q, ExternalID: %q)", config.AssumeRoleARN, config.AssumeRoleSessionName, config.AssumeRoleExternalID)
 	}
 
+	config.Endpoints = make(map[string]string)
+
+	if v, ok := d.GetOk("endpoints"); ok {
+		endpointsSet := v.(*schema.Set)
+
+		for _, endpointsSetI := range endpointsSet.List() {
+			endpoints := endpointsSetI.(map[string]interface{})
+			for _, endpointServiceName := range endpointServiceNames {
+				config.Endpoints[endpointServiceName] = endpoints[endpointServiceName].(string)
+			}
+		}
+	}
+
 	if v, ok := d.GetOk("allowed_account_ids"); ok {
 		for _, accountIDRaw := range v.(*schema.Set).List() {
 			config.AllowedAccountIds = append(config.AllowedAccountIds, accountIDRaw.(string))
@@ -222,7 +485,7 @@ func providerConfigure(d *schema.ResourceData, terraformVersion string) (interfa
 		}
 	}
 
-	return config.Client()
+	return config
 }
 
 func assumeRoleSchema() *schema.Schema {
@@ -300,3 +563,113 @@ func endpointsSchema() *schema.Schema {
 		},
 	}
 }
+
+func expandProviderDefaultTags(l []interface{}) *DefaultConfig {
+	if len(l) == 0 || l[0] == nil {
+		return nil
+	}
+
+	defaultConfig := &DefaultConfig{}
+	m := l[0].(map[string]interface{})
+
+	if v, ok := m["tags"].(map[string]interface{}); ok {
+		defaultConfig.Tags = New(v)
+	}
+
+	return defaultConfig
+}
+
+func expandProviderIgnoreTags(l []interface{}) *IgnoreConfig {
+	if len(l) == 0 || l[0] == nil {
+		return nil
+	}
+
+	ignoreConfig := &IgnoreConfig{}
+	m := l[0].(map[string]interface{})
+
+	if v, ok := m["keys"].(*schema.Set); ok {
+		ignoreConfig.Keys = New(v.List())
+	}
+
+	if v, ok := m["key_prefixes"].(*schema.Set); ok {
+		ignoreConfig.KeyPrefixes = New(v.List())
+	}
+
+	return ignoreConfig
+}
+
+// expandProviderAge returns the age block configuration or, when the block is
+// not set, the identity from the ENCRYPTEDSSM_AGE_IDENTITY or
+// ENCRYPTEDSSM_AGE_IDENTITY_FILE environment variables.
+func expandProviderAge(l []interface{}) *AgeConfig {
+	ageConfig := &AgeConfig{}
+
+	if len(l) == 0 || l[0] == nil {
+		ageConfig.Identity = os.Getenv("ENCRYPTEDSSM_AGE_IDENTITY")
+		ageConfig.IdentityFile = os.Getenv("ENCRYPTEDSSM_AGE_IDENTITY_FILE")
+	} else {
+		m := l[0].(map[string]interface{})
+
+		ageConfig.Identity, _ = m["identity"].(string)
+		ageConfig.IdentityFile, _ = m["identity_file"].(string)
+		ageConfig.EncryptedIdentity, _ = m["encrypted_identity"].(string)
+	}
+
+	if *ageConfig == (AgeConfig{}) {
+		return nil
+	}
+
+	return ageConfig
+}
+
+// expandProviderPgp returns the pgp block configuration or, when the block is
+// not set, the private key from the ENCRYPTEDSSM_PGP_PRIVATE_KEY or
+// ENCRYPTEDSSM_PGP_PRIVATE_KEY_FILE and ENCRYPTEDSSM_PGP_PASSPHRASE
+// environment variables.
+func expandProviderPgp(l []interface{}) *PgpConfig {
+	pgpConfig := &PgpConfig{}
+
+	if len(l) == 0 || l[0] == nil {
+		pgpConfig.PrivateKey = os.Getenv("ENCRYPTEDSSM_PGP_PRIVATE_KEY")
+		pgpConfig.PrivateKeyFile = os.Getenv("ENCRYPTEDSSM_PGP_PRIVATE_KEY_FILE")
+		pgpConfig.Passphrase = os.Getenv("ENCRYPTEDSSM_PGP_PASSPHRASE")
+	} else {
+		m := l[0].(map[string]interface{})
+
+		pgpConfig.PrivateKey, _ = m["private_key"].(string)
+		pgpConfig.PrivateKeyFile, _ = m["private_key_file"].(string)
+		pgpConfig.EncryptedPrivateKey, _ = m["encrypted_private_key"].(string)
+		pgpConfig.Passphrase, _ = m["passphrase"].(string)
+	}
+
+	if pgpConfig.PrivateKey == "" && pgpConfig.PrivateKeyFile == "" && pgpConfig.EncryptedPrivateKey == "" {
+		return nil
+	}
+
+	return pgpConfig
+}
+
+func expandProviderVault(l []interface{}) *VaultConfig {
+	if len(l) == 0 || l[0] == nil {
+		return nil
+	}
+
+	vaultConfig := &VaultConfig{}
+	m := l[0].(map[string]interface{})
+
+	vaultConfig.Address, _ = m["address"].(string)
+	vaultConfig.Namespace, _ = m["namespace"].(string)
+	vaultConfig.Token, _ = m["token"].(string)
+	vaultConfig.Mount, _ = m["mount"].(string)
+	vaultConfig.KeyName, _ = m["key_name"].(string)
+
+	if v, ok := m["approle"].([]interface{}); ok && len(v) > 0 && v[0] != nil {
+		approle := v[0].(map[string]interface{})
+
+		vaultConfig.AppRoleMount, _ = approle["mount"].(string)
+		vaultConfig.AppRoleRoleId, _ = approle["role_id"].(string)
+		vaultConfig.AppRoleSecretId, _ = approle["secret_id"].(string)
+	}
+
+	return vaultConfig
+}
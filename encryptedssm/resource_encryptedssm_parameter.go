@@ -1,11 +1,17 @@
 package encryptedssm
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -21,16 +27,67 @@ import (
 const (
 	// Maximum amount of time to wait for asynchronous validation on SSM Parameter creation.
 	ssmParameterCreationValidationTimeout = 2 * time.Minute
+
+	// Data type SSM validates as an EC2 AMI ID.
+	ssmParameterDataTypeEc2Image = "aws:ec2:image"
+
+	// Maximum plaintext size accepted by kms:Encrypt, larger values are envelope encrypted.
+	kmsEncryptMaxPlaintextSize = 4096
+)
+
+var (
+	// Arguments the decrypted parameter value depends on.
+	ssmParameterValueAttributes = []string{
+		"name",
+		"encrypted_value",
+		"encrypted_list",
+		"encrypted_fields",
+		"plaintext_fields",
+		"value_template",
+		"encrypted_values",
+		"encryption_key",
+		"encryption_scheme",
+		"encryption_algorithm",
+		"encryption_context",
+		"bind_name_context",
+	}
+
+	// Arguments naming the KMS key the value hash key is generated under, in
+	// order of precedence.
+	ssmParameterValueHashKeyAttributes = []string{
+		"value_hash_key_id",
+		"ssm_key_id",
+		"encryption_key",
+	}
+
+	// Arguments besides the ciphertext a ciphertext is decrypted with.
+	ssmParameterDecryptionAttributes = []string{
+		"name",
+		"encryption_key",
+		"encryption_scheme",
+		"encryption_algorithm",
+		"encryption_context",
+		"bind_name_context",
+	}
+
+	// Format SSM requires of values with the aws:ec2:image data type.
+	ssmParameterEc2ImageIdRegexp = regexp.MustCompile(`^ami-([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+	// Placeholders in value_template referencing an encrypted_values entry,
+	// such as {{ password }}.
+	ssmParameterTemplatePlaceholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
 )
 
-func resourceAwsSsmParameter() *schema.Resource {
+func resourceAwsSsmParameter(providerMeta func() interface{}) *schema.Resource {
+	suppressEquivalentCiphertext := suppressEquivalentCiphertextDiff(providerMeta, ssmParameterDecryptionAttributes)
+
 	return &schema.Resource{
 		Create: resourceAwsSsmParameterPut,
 		Read:   resourceAwsSsmParameterRead,
 		Update: resourceAwsSsmParameterPut,
 		Delete: resourceAwsSsmParameterDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceAwsSsmParameterImport,
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -56,19 +113,98 @@ func resourceAwsSsmParameter() *schema.Resource {
 				Type:     schema.TypeString,
 				Required: true,
 				ValidateFunc: validation.StringInSlice([]string{
+					ssm.ParameterTypeString,
+					ssm.ParameterTypeStringList,
 					ssm.ParameterTypeSecureString,
 				}, false),
 			},
 			"encrypted_value": {
-				Type:      schema.TypeString,
-				Required:  true,
-				Sensitive: false,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Sensitive:        false,
+				ExactlyOneOf:     []string{"encrypted_value", "encrypted_list", "encrypted_fields", "value_template"},
+				DiffSuppressFunc: suppressEquivalentCiphertext,
+			},
+			"encrypted_list": {
+				Type:             schema.TypeList,
+				Optional:         true,
+				Elem:             &schema.Schema{Type: schema.TypeString},
+				ExactlyOneOf:     []string{"encrypted_value", "encrypted_list", "encrypted_fields", "value_template"},
+				DiffSuppressFunc: suppressEquivalentCiphertext,
+			},
+			"encrypted_fields": {
+				Type:             schema.TypeMap,
+				Optional:         true,
+				Elem:             &schema.Schema{Type: schema.TypeString},
+				ExactlyOneOf:     []string{"encrypted_value", "encrypted_list", "encrypted_fields", "value_template"},
+				DiffSuppressFunc: suppressEquivalentCiphertext,
+			},
+			"plaintext_fields": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				RequiredWith: []string{"encrypted_fields"},
+			},
+			"value_template": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ExactlyOneOf: []string{"encrypted_value", "encrypted_list", "encrypted_fields", "value_template"},
+				RequiredWith: []string{"encrypted_values"},
+			},
+			"encrypted_values": {
+				Type:             schema.TypeMap,
+				Optional:         true,
+				Elem:             &schema.Schema{Type: schema.TypeString},
+				RequiredWith:     []string{"value_template"},
+				DiffSuppressFunc: suppressEquivalentCiphertext,
 			},
 			"encryption_key": {
 				Type:      schema.TypeString,
-				Required:  true,
+				Optional:  true,
 				Sensitive: false,
 			},
+			"encryption_scheme": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      encryptionSchemeKms,
+				ValidateFunc: validation.StringInSlice(encryptionSchemes, false),
+			},
+			"ssm_key_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"value_hash_key_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"key_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"encryption_algorithm": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					kms.EncryptionAlgorithmSpecSymmetricDefault,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+				}, false),
+			},
+			"encryption_context": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"bind_name_context": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"verify_decryption": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
 			"arn": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -79,7 +215,7 @@ func resourceAwsSsmParameter() *schema.Resource {
 				Optional: true,
 				Computed: true,
 				ValidateFunc: validation.StringInSlice([]string{
-					"aws:ec2:image",
+					ssmParameterDataTypeEc2Image,
 					"text",
 				}, false),
 			},
@@ -95,21 +231,526 @@ func resourceAwsSsmParameter() *schema.Resource {
 				Type:     schema.TypeInt,
 				Computed: true,
 			},
-			"tags": tagsSchema(),
+			"value_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"item_hashes": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"field_hashes": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"value_hash_key": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"tags":     tagsSchema(),
+			"tags_all": tagsSchemaComputed(),
 		},
 
-		CustomizeDiff: customdiff.All(
-			// Prevent the following error during tier update from Advanced to Standard:
-			// ValidationException: This parameter uses the advanced-parameter tier. You can't downgrade a parameter from the advanced-parameter tier to the standard-parameter tier. If necessary, you can delete the advanced parameter and recreate it as a standard parameter.
-			customdiff.ForceNewIfChange("tier", func(_ context.Context, old, new, meta interface{}) bool {
-				return old.(string) == ssm.ParameterTierAdvanced && new.(string) == ssm.ParameterTierStandard
-			}),
-		),
+		CustomizeDiff: customdiff.All(
+			// Prevent the following error during tier update from Advanced to Standard:
+			// ValidationException: This parameter uses the advanced-parameter tier. You can't downgrade a parameter from the advanced-parameter tier to the standard-parameter tier. If necessary, you can delete the advanced parameter and recreate it as a standard parameter.
+			customdiff.ForceNewIfChange("tier", func(_ context.Context, old, new, meta interface{}) bool {
+				return old.(string) == ssm.ParameterTierAdvanced && new.(string) == ssm.ParameterTierStandard
+			}),
+			resourceAwsSsmParameterCustomizeDiffScheme,
+			resourceAwsSsmParameterCustomizeDiffSsmKeyId,
+			resourceAwsSsmParameterCustomizeDiffSsmKeySpec,
+			resourceAwsSsmParameterCustomizeDiffCiphertext,
+			resourceAwsSsmParameterCustomizeDiffValue,
+			resourceAwsSsmParameterCustomizeDiffValueHashKeyId,
+			resourceAwsSsmParameterCustomizeDiffValueHash,
+			SetTagsDiff,
+		),
+	}
+}
+
+// resourceAwsSsmParameterCustomizeDiffScheme requires encryption_key except
+// with the vault encryption scheme, which defaults to the Transit key of the
+// provider, and rejects the KMS only arguments for the age, pgp and vault
+// schemes, whose ciphertexts are decrypted with the provider configuration.
+func resourceAwsSsmParameterCustomizeDiffScheme(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	scheme := diff.Get("encryption_scheme").(string)
+	if !diff.NewValueKnown("encryption_scheme") {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+
+	if _, ok := diff.GetOk("encryption_key"); !ok && diff.NewValueKnown("encryption_key") && scheme != encryptionSchemeVault {
+		return fmt.Errorf("encryptedssm_parameter %q: encryption_key is required with encryption_scheme %q", name, scheme)
+	}
+
+	if scheme == encryptionSchemeKms {
+		return nil
+	}
+
+	for _, k := range []string{"encryption_algorithm", "encryption_context", "bind_name_context"} {
+		if _, ok := diff.GetOk(k); ok {
+			return fmt.Errorf("encryptedssm_parameter %q: %s is not supported with encryption_scheme %q", name, k, scheme)
+		}
+	}
+
+	return nil
+}
+
+// resourceAwsSsmParameterCustomizeDiffSsmKeyId requires ssm_key_id when
+// encryption_key is an asymmetric key or, with the vault encryption scheme, a
+// Transit key, as SSM only stores SecureStrings with symmetric KMS keys and
+// the value hash key is generated under the storage key.
+func resourceAwsSsmParameterCustomizeDiffSsmKeyId(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if _, ok := diff.GetOk("ssm_key_id"); ok || !diff.NewValueKnown("ssm_key_id") {
+		return nil
+	}
+
+	if diff.Get("encryption_scheme").(string) == encryptionSchemeVault {
+		return fmt.Errorf("encryptedssm_parameter %q: ssm_key_id is required with encryption_scheme %q, set it to a symmetric KMS key", diff.Get("name").(string), encryptionSchemeVault)
+	}
+
+	algorithm := diff.Get("encryption_algorithm").(string)
+	if algorithm == "" || algorithm == kms.EncryptionAlgorithmSpecSymmetricDefault {
+		return nil
+	}
+
+	return fmt.Errorf("encryptedssm_parameter %q: ssm_key_id is required with encryption_algorithm %q, set it to a symmetric KMS key", diff.Get("name").(string), algorithm)
+}
+
+// resourceAwsSsmParameterCustomizeDiffSsmKeySpec rejects an asymmetric SSM
+// storage key during plan. SSM only stores SecureStrings with symmetric keys
+// and the value hash key is generated under the same key.
+func resourceAwsSsmParameterCustomizeDiffSsmKeySpec(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() != "" && !diff.HasChange("encryption_key") && !diff.HasChange("ssm_key_id") {
+		return nil
+	}
+
+	if !diff.NewValueKnown("encryption_key") || !diff.NewValueKnown("ssm_key_id") {
+		return nil
+	}
+
+	attr := "encryption_key"
+	if _, ok := diff.GetOk("ssm_key_id"); ok {
+		attr = "ssm_key_id"
+	}
+
+	// encryption_key names a Transit key with the vault scheme, the missing
+	// ssm_key_id is reported by resourceAwsSsmParameterCustomizeDiffSsmKeyId
+	if attr == "encryption_key" && diff.Get("encryption_scheme").(string) == encryptionSchemeVault {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+	keyId := ssmParameterKeyId(diff)
+
+	keySpec, err := kmsKeySpec(keyId, meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+	}
+
+	if keySpec != kms.CustomerMasterKeySpecSymmetricDefault {
+		return fmt.Errorf("encryptedssm_parameter %q: %s %s is an asymmetric %s KMS key, set ssm_key_id to a symmetric KMS key to store the parameter and its value hash key with", name, attr, keyId, keySpec)
+	}
+
+	return nil
+}
+
+// resourceAwsSsmParameterCustomizeDiffCiphertext validates changed ciphertexts
+// during plan rather than failing part way through apply. KMS ciphertexts must
+// be valid base64 and envelopes must have been encrypted with encryption_key.
+// verify_decryption opts in to decrypting every ciphertext in full, including
+// the other encryption schemes, which also checks the key of KMS ciphertext
+// blobs as only KMS knows it.
+func resourceAwsSsmParameterCustomizeDiffCiphertext(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if !ssmParameterValueKnown(diff) || !ssmParameterValueChanged(diff, "verify_decryption") {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+
+	ciphertexts := map[string]string{}
+	if v, ok := diff.GetOk("encrypted_value"); ok {
+		ciphertexts["encrypted_value"] = v.(string)
+	}
+	for i, v := range diff.Get("encrypted_list").([]interface{}) {
+		ciphertexts[fmt.Sprintf("encrypted_list.%d", i)], _ = v.(string)
+	}
+	for _, attr := range []string{"encrypted_fields", "encrypted_values"} {
+		for k, v := range diff.Get(attr).(map[string]interface{}) {
+			ciphertexts[attr+"."+k], _ = v.(string)
+		}
+	}
+
+	// age and OpenPGP ciphertexts may be armored rather than base64 and are
+	// only checked by decrypting them
+	isKms := diff.Get("encryption_scheme").(string) == encryptionSchemeKms
+	if isKms {
+		if err := validateKmsCiphertexts(diff, ciphertexts, meta); err != nil {
+			return err
+		}
+	}
+
+	if !diff.Get("verify_decryption").(bool) {
+		return nil
+	}
+
+	for k, ciphertext := range ciphertexts {
+		if _, err := decryptCiphertext(diff, ciphertext, meta); err != nil {
+			if isKms {
+				if err := validateKmsCiphertextKey(diff, k, ciphertext, meta); err != nil {
+					return err
+				}
+			}
+
+			return fmt.Errorf("encryptedssm_parameter %q: %s could not be decrypted: %s", name, k, err)
+		}
+	}
+
+	return nil
+}
+
+// validateKmsCiphertexts checks KMS ciphertexts are valid base64 and that
+// envelopes were encrypted with encryption_key, which is resolved once for
+// all of them.
+func validateKmsCiphertexts(diff *schema.ResourceDiff, ciphertexts map[string]string, meta interface{}) error {
+	name := diff.Get("name").(string)
+
+	var keyArn string
+	for k, ciphertext := range ciphertexts {
+		blob, err := base64.StdEncoding.DecodeString(ciphertext)
+		if err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s is not valid base64: %s", name, k, err)
+		}
+
+		env, ok := parseEnvelope(blob)
+		if !ok {
+			continue
+		}
+
+		if _, err := env.encryptedDataKey(); err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s: %s", name, k, err)
+		}
+
+		if env.KeyId == "" {
+			continue
+		}
+
+		if keyArn == "" {
+			if keyArn, err = resolveKmsKeyArn(diff.Get("encryption_key").(string), meta); err != nil {
+				return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+			}
+		}
+
+		envKeyArn, err := resolveKmsKeyArn(env.KeyId, meta)
+		if err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+
+		if envKeyArn != keyArn {
+			return fmt.Errorf("encryptedssm_parameter %q: %s was encrypted with KMS key %s, not encryption_key %s", name, k, envKeyArn, keyArn)
+		}
+	}
+
+	return nil
+}
+
+// validateKmsCiphertextKey explains why a KMS ciphertext could not be
+// decrypted when it was encrypted with another key than encryption_key,
+// returning nil when the key matches or cannot be determined.
+func validateKmsCiphertextKey(diff *schema.ResourceDiff, k, ciphertext string, meta interface{}) error {
+	blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil
+	}
+
+	if env, ok := parseEnvelope(blob); ok {
+		if blob, err = env.encryptedDataKey(); err != nil {
+			return nil
+		}
+	}
+
+	ciphertextKeyId, err := kmsCiphertextKeyId(diff, blob, meta)
+	if err != nil {
+		return nil
+	}
+
+	keyArn, err := resolveKmsKeyArn(diff.Get("encryption_key").(string), meta)
+	if err != nil {
+		return nil
+	}
+
+	ciphertextKeyArn, err := resolveKmsKeyArn(ciphertextKeyId, meta)
+	if err != nil || ciphertextKeyArn == keyArn {
+		return nil
+	}
+
+	return fmt.Errorf("encryptedssm_parameter %q: %s was encrypted with KMS key %s, not encryption_key %s", diff.Get("name").(string), k, ciphertextKeyArn, keyArn)
+}
+
+// kmsCiphertextKeyId returns the key a KMS ciphertext blob was encrypted
+// with by decrypting it. Symmetric ciphertexts are decrypted without a key
+// so KMS reports the key they were encrypted with, asymmetric ones require
+// the key and fail with any other.
+func kmsCiphertextKeyId(d resourceGetter, blob []byte, meta interface{}) (string, error) {
+	input := &kms.DecryptInput{
+		CiphertextBlob: blob,
+	}
+
+	if v, ok := d.GetOk("encryption_algorithm"); ok && v.(string) != kms.EncryptionAlgorithmSpecSymmetricDefault {
+		input.EncryptionAlgorithm = aws.String(v.(string))
+		input.KeyId = aws.String(d.Get("encryption_key").(string))
+	}
+
+	if v := expandEncryptionContext(d); len(v) > 0 {
+		input.EncryptionContext = v
+	}
+
+	output, err := kmsDecrypt(input, meta)
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.KeyId), nil
+}
+
+// resourceAwsSsmParameterCustomizeDiffValue decrypts the value during plan to
+// check it against allowed_pattern and data_type, which SSM would otherwise
+// only reject during apply. Errors never include the decrypted value.
+func resourceAwsSsmParameterCustomizeDiffValue(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	allowedPattern := diff.Get("allowed_pattern").(string)
+
+	// data_type is computed, so unknown when it is not configured on create,
+	// which leaves allowed_pattern to check
+	var dataType string
+	if diff.NewValueKnown("data_type") {
+		dataType = diff.Get("data_type").(string)
+	}
+
+	if allowedPattern == "" && dataType != ssmParameterDataTypeEc2Image {
+		return nil
+	}
+
+	if !diff.NewValueKnown("allowed_pattern") || !ssmParameterValueKnown(diff) {
+		return nil
+	}
+
+	if !ssmParameterValueChanged(diff, "allowed_pattern", "data_type") {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+
+	value, err := ssmParameterValue(diff, meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+	}
+
+	if allowedPattern != "" {
+		// SSM evaluates allowed_pattern as a Java regular expression. Patterns
+		// using syntax RE2 does not support are left for SSM to validate.
+		re, err := regexp.Compile(allowedPattern)
+		if err != nil {
+			log.Printf("[WARN] SSM Parameter (%s) allowed_pattern cannot be validated during plan: %s", name, err)
+		} else if !re.MatchString(value) {
+			return fmt.Errorf("encryptedssm_parameter %q: decrypted value does not match allowed_pattern %q", name, allowedPattern)
+		}
+	}
+
+	if dataType == ssmParameterDataTypeEc2Image && !ssmParameterEc2ImageIdRegexp.MatchString(value) {
+		return fmt.Errorf("encryptedssm_parameter %q: decrypted value is not an AMI ID in the format ami-12345678 or ami-1234567890abcdef0 required by data_type %q", name, dataType)
+	}
+
+	return nil
+}
+
+// resourceAwsSsmParameterCustomizeDiffValueHashKeyId rejects an asymmetric
+// value_hash_key_id during plan and generates a new value hash key when the
+// KMS key it is generated under changes, as the hashes are then keyed by the
+// new key.
+func resourceAwsSsmParameterCustomizeDiffValueHashKeyId(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	for _, k := range ssmParameterValueHashKeyAttributes {
+		if !diff.NewValueKnown(k) {
+			if diff.Id() == "" {
+				return nil
+			}
+
+			return ssmParameterSetNewComputedValueHashKey(diff)
+		}
+	}
+
+	if v, ok := diff.GetOk("value_hash_key_id"); ok && (diff.Id() == "" || diff.HasChange("value_hash_key_id")) {
+		name := diff.Get("name").(string)
+		keyId := v.(string)
+
+		keySpec, err := kmsKeySpec(keyId, meta)
+		if err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+
+		if keySpec != kms.CustomerMasterKeySpecSymmetricDefault {
+			return fmt.Errorf("encryptedssm_parameter %q: value_hash_key_id %s is an asymmetric %s KMS key, set it to a symmetric KMS key", name, keyId, keySpec)
+		}
+	}
+
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if o, n := ssmParameterValueHashKeyIdChange(diff); o == n {
+		return nil
+	}
+
+	return ssmParameterSetNewComputedValueHashKey(diff)
+}
+
+// ssmParameterSetNewComputedValueHashKey marks the value hash key, and the
+// hashes keyed by it, as generated anew during apply.
+func ssmParameterSetNewComputedValueHashKey(diff *schema.ResourceDiff) error {
+	for _, k := range []string{"value_hash_key", "value_hash", "item_hashes", "field_hashes"} {
+		if err := diff.SetNewComputed(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resourceAwsSsmParameterCustomizeDiffValueHash decrypts the configured value
+// during plan and compares its hash with the hash of the value last read from
+// SSM, so a value changed in SSM or in configuration shows as a change of
+// value_hash, item_hashes and field_hashes.
+func resourceAwsSsmParameterCustomizeDiffValueHash(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	if !ssmParameterValueKnown(diff) {
+		if ssmParameterValueChanged(diff) {
+			for _, k := range []string{"value_hash", "item_hashes", "field_hashes"} {
+				if err := diff.SetNewComputed(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// The hashes are computed anew with a new value hash key
+	if !diff.NewValueKnown("value_hash_key") {
+		return nil
+	}
+
+	hashKeyBlob := diff.Get("value_hash_key").(string)
+	if hashKeyBlob == "" {
+		return nil
+	}
+
+	name := diff.Get("name").(string)
+
+	hashKey, err := decryptValueHashKey(hashKeyBlob, meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+	}
+
+	var value string
+	var itemHashes []string
+	fieldHashes := make(map[string]string)
+	if _, ok := diff.GetOk("encrypted_list"); ok {
+		items, err := decryptEncryptedList(diff, meta)
+		if err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+
+		value = strings.Join(items, ",")
+		itemHashes = valueHashes(hashKey, items)
+	} else if _, ok := diff.GetOk("encrypted_fields"); ok {
+		fields, err := ssmParameterFields(diff, meta)
+		if err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+
+		if value, err = marshalSsmParameterFields(fields); err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+
+		fieldHashes = valueHashMap(hashKey, fields)
+	} else {
+		if value, err = ssmParameterValue(diff, meta); err != nil {
+			return fmt.Errorf("encryptedssm_parameter %q: %s", name, err)
+		}
+	}
+
+	if o, _ := diff.GetChange("value_hash"); o.(string) != valueHash(hashKey, value) {
+		if err := diff.SetNew("value_hash", valueHash(hashKey, value)); err != nil {
+			return err
+		}
+	}
+
+	if o, _ := diff.GetChange("item_hashes"); strings.Join(expandStringList(o.([]interface{})), ",") != strings.Join(itemHashes, ",") {
+		if err := diff.SetNew("item_hashes", itemHashes); err != nil {
+			return err
+		}
+	}
+
+	if o, _ := diff.GetChange("field_hashes"); !reflect.DeepEqual(expandStringMap(o.(map[string]interface{})), fieldHashes) {
+		if err := diff.SetNew("field_hashes", fieldHashes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ssmParameterValueKnown reports whether every argument the decrypted value
+// depends on is known during plan.
+func ssmParameterValueKnown(diff *schema.ResourceDiff) bool {
+	for _, k := range ssmParameterValueAttributes {
+		if !diff.NewValueKnown(k) {
+			return false
+		}
+	}
+
+	for i := range diff.Get("encrypted_list").([]interface{}) {
+		if !diff.NewValueKnown(fmt.Sprintf("encrypted_list.%d", i)) {
+			return false
+		}
+	}
+
+	for _, attr := range []string{"encrypted_fields", "plaintext_fields", "encrypted_values"} {
+		for k := range diff.Get(attr).(map[string]interface{}) {
+			if !diff.NewValueKnown(attr + "." + k) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ssmParameterValueChanged reports whether the resource is new or any of the
+// arguments the decrypted value depends on, or the given extra arguments,
+// have changed.
+func ssmParameterValueChanged(diff *schema.ResourceDiff, extra ...string) bool {
+	if diff.Id() == "" {
+		return true
+	}
+
+	for _, k := range append(ssmParameterValueAttributes, extra...) {
+		if diff.HasChange(k) {
+			return true
+		}
 	}
+
+	return false
 }
 
 func resourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error {
 	ssmconn := meta.(*AWSClient).ssmconn
+	defaultTagsConfig := meta.(*AWSClient).DefaultTagsConfig
 	ignoreTagsConfig := meta.(*AWSClient).IgnoreTagsConfig
 
 	log.Printf("[DEBUG] Reading SSM Parameter: %s", d.Id())
@@ -124,7 +765,7 @@ func resourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error
 		var err error
 		resp, err = ssmconn.GetParameter(input)
 
-		if isAWSErr(err, ssm.ErrCodeParameterNotFound, "") && d.IsNewResource() && d.Get("data_type").(string) == "aws:ec2:image" {
+		if isAWSErr(err, ssm.ErrCodeParameterNotFound, "") && d.IsNewResource() && d.Get("data_type").(string) == ssmParameterDataTypeEc2Image {
 			return resource.RetryableError(fmt.Errorf("error reading SSM Parameter (%s) after creation: this can indicate that the provided parameter value could not be validated by SSM", d.Id()))
 		}
 
@@ -153,32 +794,35 @@ func resourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error
 	name := *param.Name
 	encValue := *param.Value
 
-	base64Blob, err := base64.StdEncoding.DecodeString(d.Get("encrypted_value").(string))
+	d.Set("name", name)
+	d.Set("type", param.Type)
+	d.Set("version", param.Version)
+
+	// Record hashes of what is in SSM, plan compares them to the configured value
+	hashKey, err := ssmParameterValueHashKey(d, meta)
 	if err != nil {
 		return err
 	}
 
-	decryptinput := &kms.DecryptInput{
-		KeyId:          aws.String(d.Get("encryption_key").(string)),
-		CiphertextBlob: base64Blob,
+	d.Set("value_hash", valueHash(hashKey, encValue))
+
+	var itemHashes []string
+	if _, ok := d.GetOk("encrypted_list"); ok {
+		itemHashes = valueHashes(hashKey, strings.Split(encValue, ","))
 	}
 
-	result, err := kmsDecrypt(decryptinput, meta)
-	if err != nil {
-		return fmt.Errorf("Error decrypting with KMS: %s", err)
+	if err := d.Set("item_hashes", itemHashes); err != nil {
+		return fmt.Errorf("error setting item_hashes: %s", err)
 	}
 
-	var encrypted_value string
-	if string(result.Plaintext) == string(encValue) {
-		encrypted_value = d.Get("encrypted_value").(string)
-	} else {
-		encrypted_value = "Outdated sensitive value"
+	var fieldHashes map[string]string
+	if _, ok := d.GetOk("encrypted_fields"); ok {
+		fieldHashes = ssmParameterFieldHashes(hashKey, encValue)
 	}
 
-	d.Set("name", name)
-	d.Set("type", param.Type)
-	d.Set("encrypted_value", encrypted_value)
-	d.Set("version", param.Version)
+	if err := d.Set("field_hashes", fieldHashes); err != nil {
+		return fmt.Errorf("error setting field_hashes: %s", err)
+	}
 
 	describeParamsInput := &ssm.DescribeParametersInput{
 		ParameterFilters: []*ssm.ParameterStringFilter{
@@ -202,6 +846,10 @@ func resourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error
 
 	detail := describeResp.Parameters[0]
 	d.Set("key_id", detail.KeyId)
+	// Surface a storage key changed outside of Terraform as a diff on ssm_key_id
+	if keyId := aws.StringValue(detail.KeyId); keyId != "" && keyId != ssmParameterKeyId(d) {
+		d.Set("ssm_key_id", keyId)
+	}
 	d.Set("description", detail.Description)
 	d.Set("tier", ssm.ParameterTierStandard)
 	if detail.Tier != nil {
@@ -216,15 +864,115 @@ func resourceAwsSsmParameterRead(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("error listing tags for SSM Parameter (%s): %s", name, err)
 	}
 
-	if err := d.Set("tags", tags.IgnoreAws().IgnoreConfig(ignoreTagsConfig).Map()); err != nil {
+	tags = tags.IgnoreAws().IgnoreConfig(ignoreTagsConfig)
+
+	//lintignore:AWSR002
+	if err := d.Set("tags", tags.RemoveDefaultConfig(defaultTagsConfig).Map()); err != nil {
 		return fmt.Errorf("error setting tags: %s", err)
 	}
 
+	if err := d.Set("tags_all", tags.Map()); err != nil {
+		return fmt.Errorf("error setting tags_all: %s", err)
+	}
+
 	d.Set("arn", param.ARN)
 
 	return nil
 }
 
+// resourceAwsSsmParameterImport imports a parameter using an ID of the form
+// NAME,KMS-KEY-ID. The current value is encrypted under the KMS key, without
+// encryption context, so state holds a usable encrypted_value and
+// encryption_key that can be copied into configuration.
+// ParameterImportArguments are the arguments an encryptedssm_parameter is
+// imported with, given as JSON after the name and KMS key of the import ID so
+// the imported state matches a configuration that sets them.
+type ParameterImportArguments struct {
+	BindNameContext   bool              `json:"bind_name_context,omitempty"`
+	EncryptionContext map[string]string `json:"encryption_context,omitempty"`
+	ValueHashKeyId    string            `json:"value_hash_key_id,omitempty"`
+}
+
+// ParameterImportId returns the import ID of the parameter name with its
+// value encrypted under keyId with args.
+func ParameterImportId(name, keyId string, args ParameterImportArguments) (string, error) {
+	id := name + "," + keyId
+	if !args.BindNameContext && len(args.EncryptionContext) == 0 && args.ValueHashKeyId == "" {
+		return id, nil
+	}
+
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	return id + "," + string(b), nil
+}
+
+func resourceAwsSsmParameterImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	ssmconn := meta.(*AWSClient).ssmconn
+
+	idParts := strings.SplitN(d.Id(), ",", 3)
+	if len(idParts) < 2 || idParts[0] == "" || idParts[1] == "" {
+		return nil, fmt.Errorf("unexpected format of ID (%q), expected NAME,KMS-KEY-ID[,ARGUMENTS]", d.Id())
+	}
+
+	name := idParts[0]
+	keyId := idParts[1]
+
+	var args ParameterImportArguments
+	if len(idParts) == 3 {
+		dec := json.NewDecoder(strings.NewReader(idParts[2]))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&args); err != nil {
+			return nil, fmt.Errorf("unexpected format of ID (%q), expected ARGUMENTS to be a JSON object of bind_name_context, encryption_context and value_hash_key_id: %s", d.Id(), err)
+		}
+	}
+
+	// The value is encrypted without encryption_algorithm, which asymmetric
+	// keys require, and the key also becomes the SSM storage key
+	keySpec, err := kmsKeySpec(keyId, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if keySpec != kms.CustomerMasterKeySpecSymmetricDefault {
+		return nil, fmt.Errorf("KMS key %s is an asymmetric %s key, SSM Parameters can only be imported with a symmetric KMS key", keyId, keySpec)
+	}
+
+	resp, err := ssmconn.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading SSM Parameter (%s): %w", name, err)
+	}
+
+	d.SetId(name)
+	d.Set("name", name)
+	d.Set("encryption_key", keyId)
+	d.Set("encryption_scheme", encryptionSchemeKms)
+	d.Set("bind_name_context", args.BindNameContext)
+	d.Set("encryption_context", args.EncryptionContext)
+	d.Set("value_hash_key_id", args.ValueHashKeyId)
+	d.Set("verify_decryption", false)
+
+	encryptedValue, err := encryptValue(&kms.EncryptInput{
+		KeyId:             aws.String(keyId),
+		Plaintext:         []byte(aws.StringValue(resp.Parameter.Value)),
+		EncryptionContext: expandParameterEncryptionContext(d, name),
+	}, meta)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting SSM Parameter (%s): %s", name, err)
+	}
+
+	log.Printf("[INFO] SSM Parameter (%s) imported with encrypted_value: %s", name, encryptedValue)
+
+	d.Set("encrypted_value", encryptedValue)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceAwsSsmParameterDelete(d *schema.ResourceData, meta interface{}) error {
 	ssmconn := meta.(*AWSClient).ssmconn
 
@@ -245,26 +993,16 @@ func resourceAwsSsmParameterPut(d *schema.ResourceData, meta interface{}) error
 
 	log.Printf("[INFO] Creating SSM Parameter: %s", d.Get("name").(string))
 
-	base64Blob, err := base64.StdEncoding.DecodeString(d.Get("encrypted_value").(string))
+	value, err := ssmParameterValue(d, meta)
 	if err != nil {
 		return err
 	}
 
-	input := &kms.DecryptInput{
-		KeyId:          aws.String(d.Get("encryption_key").(string)),
-		CiphertextBlob: base64Blob,
-	}
-
-	result, err := kmsDecrypt(input, meta)
-	if err != nil {
-		return fmt.Errorf("Error decrypting with KMS: %s", err)
-	}
-
 	paramInput := &ssm.PutParameterInput{
 		Name:           aws.String(d.Get("name").(string)),
 		Type:           aws.String(d.Get("type").(string)),
 		Tier:           aws.String(d.Get("tier").(string)),
-		Value:          aws.String(string(result.Plaintext)),
+		Value:          aws.String(value),
 		Overwrite:      aws.Bool(shouldUpdateSsmParameter(d)),
 		AllowedPattern: aws.String(d.Get("allowed_pattern").(string)),
 	}
@@ -278,7 +1016,14 @@ func resourceAwsSsmParameterPut(d *schema.ResourceData, meta interface{}) error
 		paramInput.Description = aws.String(n.(string))
 	}
 
-	paramInput.SetKeyId(d.Get("encryption_key").(string))
+	if d.Get("type").(string) == ssm.ParameterTypeSecureString {
+		paramInput.SetKeyId(ssmParameterKeyId(d))
+	}
+
+	// Read generates a new value hash key under the new key
+	if o, n := ssmParameterValueHashKeyIdChange(d); o != n {
+		d.Set("value_hash_key", "")
+	}
 
 	log.Printf("[DEBUG] Waiting for SSM Parameter %v to be updated", d.Get("name"))
 	_, err = ssmconn.PutParameter(paramInput)
@@ -293,10 +1038,10 @@ func resourceAwsSsmParameterPut(d *schema.ResourceData, meta interface{}) error
 	}
 
 	name := d.Get("name").(string)
-	if d.HasChange("tags") {
-		o, n := d.GetChange("tags")
+	if d.HasChange("tags_all") {
+		o, n := d.GetChange("tags_all")
 
-		if err := SsmUpdateTags(ssmconn, name, ssm.ResourceTypeForTaggingParameter, o, n); err != nil {
+		if err := SsmUpdateTags(ssmconn, name, ssm.ResourceTypeForTaggingParameter, o, n, meta.(*AWSClient).IgnoreTagsConfig); err != nil {
 			return fmt.Errorf("error updating SSM Parameter (%s) tags: %s", name, err)
 		}
 	}
@@ -306,6 +1051,390 @@ func resourceAwsSsmParameterPut(d *schema.ResourceData, meta interface{}) error
 	return resourceAwsSsmParameterRead(d, meta)
 }
 
+// ssmParameterValue returns the decrypted value to store in SSM, either the
+// decrypted encrypted_value, the decrypted encrypted_list items joined into
+// a StringList, the fields marshalled into a JSON object or the rendered
+// value_template.
+func ssmParameterValue(d resourceGetter, meta interface{}) (string, error) {
+	if _, ok := d.GetOk("encrypted_list"); ok {
+		items, err := decryptEncryptedList(d, meta)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.Join(items, ","), nil
+	}
+
+	if _, ok := d.GetOk("encrypted_fields"); ok {
+		fields, err := ssmParameterFields(d, meta)
+		if err != nil {
+			return "", err
+		}
+
+		return marshalSsmParameterFields(fields)
+	}
+
+	if v, ok := d.GetOk("value_template"); ok {
+		return renderSsmParameterTemplate(d, v.(string), meta)
+	}
+
+	plaintext, err := decryptCiphertext(d, d.Get("encrypted_value").(string), meta)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// decryptEncryptedList decrypts each encrypted_list item. Items must not
+// contain commas as they are joined into a single StringList value.
+func decryptEncryptedList(d resourceGetter, meta interface{}) ([]string, error) {
+	encryptedList := d.Get("encrypted_list").([]interface{})
+	items := make([]string, 0, len(encryptedList))
+
+	for i, v := range encryptedList {
+		ciphertext, _ := v.(string)
+
+		plaintext, err := decryptCiphertext(d, ciphertext, meta)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting encrypted_list item %d: %w", i, err)
+		}
+
+		if strings.Contains(string(plaintext), ",") {
+			return nil, fmt.Errorf("encrypted_list item %d must not contain a comma", i)
+		}
+
+		items = append(items, string(plaintext))
+	}
+
+	return items, nil
+}
+
+// ssmParameterFields returns the decrypted encrypted_fields merged with
+// plaintext_fields. A field must not be set in both.
+func ssmParameterFields(d resourceGetter, meta interface{}) (map[string]string, error) {
+	fields := make(map[string]string)
+
+	for k, v := range d.Get("plaintext_fields").(map[string]interface{}) {
+		fields[k] = v.(string)
+	}
+
+	for k, v := range d.Get("encrypted_fields").(map[string]interface{}) {
+		if _, ok := fields[k]; ok {
+			return nil, fmt.Errorf("field %q is set in both encrypted_fields and plaintext_fields", k)
+		}
+
+		ciphertext, _ := v.(string)
+
+		plaintext, err := decryptCiphertext(d, ciphertext, meta)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting encrypted_fields %q: %w", k, err)
+		}
+
+		fields[k] = string(plaintext)
+	}
+
+	return fields, nil
+}
+
+// marshalSsmParameterFields returns fields as canonical JSON, an object with
+// sorted keys, no insignificant whitespace and no HTML escaping, so the same
+// fields always produce the same value.
+func marshalSsmParameterFields(fields map[string]string) (string, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(fields); err != nil {
+		return "", fmt.Errorf("error marshalling fields: %w", err)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// ssmParameterFieldHashes returns the hash of each member of a JSON object
+// value read from SSM. Members that are not strings are hashed as their JSON
+// encoding, values that are not objects have no fields.
+func ssmParameterFieldHashes(hashKey []byte, value string) map[string]string {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &members); err != nil {
+		return map[string]string{}
+	}
+
+	fields := make(map[string]string, len(members))
+	for k, raw := range members {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw)
+		}
+
+		fields[k] = s
+	}
+
+	return valueHashMap(hashKey, fields)
+}
+
+// renderSsmParameterTemplate replaces each placeholder in template with its
+// decrypted encrypted_values entry. Placeholders without an entry are an
+// error, entries may be used more than once.
+func renderSsmParameterTemplate(d resourceGetter, template string, meta interface{}) (string, error) {
+	encryptedValues := d.Get("encrypted_values").(map[string]interface{})
+	values := make(map[string]string)
+
+	for _, match := range ssmParameterTemplatePlaceholderRegexp.FindAllStringSubmatch(template, -1) {
+		k := match[1]
+		if _, ok := values[k]; ok {
+			continue
+		}
+
+		ciphertext, ok := encryptedValues[k].(string)
+		if !ok {
+			return "", fmt.Errorf("value_template placeholder %q has no encrypted_values entry", k)
+		}
+
+		plaintext, err := decryptCiphertext(d, ciphertext, meta)
+		if err != nil {
+			return "", fmt.Errorf("error decrypting encrypted_values %q: %w", k, err)
+		}
+
+		values[k] = string(plaintext)
+	}
+
+	return ssmParameterTemplatePlaceholderRegexp.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[ssmParameterTemplatePlaceholderRegexp.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
+
+// decryptCiphertext decrypts a ciphertext with the decryptor of the
+// configured encryption_scheme. KMS ciphertexts are decrypted with
+// encryption_key and the encryption context, asymmetric keys requiring
+// encryption_algorithm to be set to the RSAES_OAEP algorithm the value was
+// encrypted with. Vault ciphertexts are decrypted with the Transit key named
+// by encryption_key.
+func decryptCiphertext(d resourceGetter, ciphertext string, meta interface{}) ([]byte, error) {
+	opts := CipherOptions{
+		KeyId:             d.Get("encryption_key").(string),
+		EncryptionContext: aws.StringValueMap(expandEncryptionContext(d)),
+	}
+
+	if v, ok := d.GetOk("encryption_algorithm"); ok {
+		opts.EncryptionAlgorithm = v.(string)
+	}
+
+	return meta.(*AWSClient).decrypt(d.Get("encryption_scheme").(string), ciphertext, opts)
+}
+
+// suppressEquivalentCiphertextDiff suppresses the change of a ciphertext that
+// decrypts to the same plaintext as the ciphertext in state, such as the new
+// ciphertext a data source returns on every read. The change is kept when any
+// of decryptionAttributes changes too, as applying would otherwise decrypt the
+// ciphertext in state with the new arguments.
+func suppressEquivalentCiphertextDiff(providerMeta func() interface{}, decryptionAttributes []string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		if d.Id() == "" || old == "" || new == "" || strings.HasSuffix(k, ".#") || strings.HasSuffix(k, ".%") {
+			return false
+		}
+
+		if d.HasChanges(decryptionAttributes...) {
+			return false
+		}
+
+		meta := providerMeta()
+		if meta == nil {
+			return false
+		}
+
+		oldPlaintext, err := decryptCiphertext(d, old, meta)
+		if err != nil {
+			log.Printf("[DEBUG] Not suppressing %s change, error decrypting prior ciphertext: %s", k, err)
+			return false
+		}
+
+		newPlaintext, err := decryptCiphertext(d, new, meta)
+		if err != nil {
+			log.Printf("[DEBUG] Not suppressing %s change, error decrypting new ciphertext: %s", k, err)
+			return false
+		}
+
+		return hmac.Equal(oldPlaintext, newPlaintext)
+	}
+}
+
+// decryptValue decrypts a value in the base64 format accepted by
+// encrypted_value, either a KMS ciphertext blob or an envelope, with the key,
+// algorithm and encryption context of input.
+func decryptValue(ciphertext string, input *kms.DecryptInput, meta interface{}) ([]byte, error) {
+	base64Blob, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	env, isEnvelope := parseEnvelope(base64Blob)
+	if isEnvelope {
+		if base64Blob, err = env.encryptedDataKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	input.CiphertextBlob = base64Blob
+
+	result, err := kmsDecrypt(input, meta)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting with KMS: %s", err)
+	}
+
+	if isEnvelope {
+		return env.open(result.Plaintext)
+	}
+
+	return result.Plaintext, nil
+}
+
+// ssmParameterValueHashKey returns the HMAC key used to hash the parameter
+// value, generating one the first time the parameter is read under
+// value_hash_key_id or else the SSM storage key.
+func ssmParameterValueHashKey(d *schema.ResourceData, meta interface{}) ([]byte, error) {
+	hashKeyBlob := d.Get("value_hash_key").(string)
+
+	if hashKeyBlob == "" {
+		var err error
+		if hashKeyBlob, err = generateValueHashKey(ssmParameterValueHashKeyId(d), meta); err != nil {
+			// Key policies, such as that of the AWS managed key, may only allow
+			// the storage key to be used through SSM
+			if _, ok := d.GetOk("value_hash_key_id"); !ok {
+				err = fmt.Errorf("%s, set value_hash_key_id to a symmetric KMS key data keys can be generated with", err)
+			}
+
+			return nil, err
+		}
+
+		d.Set("value_hash_key", hashKeyBlob)
+	}
+
+	return decryptValueHashKey(hashKeyBlob, meta)
+}
+
+// ssmParameterValueHashKeyId returns the KMS key the value hash key is
+// generated under, value_hash_key_id when set and the SSM storage key
+// otherwise.
+func ssmParameterValueHashKeyId(d resourceGetter) string {
+	if v, ok := d.GetOk("value_hash_key_id"); ok {
+		return v.(string)
+	}
+
+	return ssmParameterKeyId(d)
+}
+
+// ssmParameterValueHashKeyIdChange returns the KMS key the value hash key in
+// state was generated under and the key it is generated under with the new
+// arguments.
+func ssmParameterValueHashKeyIdChange(d resourceGetter) (string, string) {
+	keyIds := [2]string{}
+	for _, k := range ssmParameterValueHashKeyAttributes {
+		o, n := d.GetChange(k)
+		for i, v := range []interface{}{o, n} {
+			if keyIds[i] == "" {
+				keyIds[i], _ = v.(string)
+			}
+		}
+	}
+
+	return keyIds[0], keyIds[1]
+}
+
+// ssmParameterKeyId returns the KMS key SSM stores the parameter with,
+// ssm_key_id when set and encryption_key otherwise. The value hash key is
+// generated under the same symmetric key.
+func ssmParameterKeyId(d resourceGetter) string {
+	if v, ok := d.GetOk("ssm_key_id"); ok {
+		return v.(string)
+	}
+
+	return d.Get("encryption_key").(string)
+}
+
+// expandEncryptionContext returns the KMS encryption context for the
+// resource, binding the parameter name when bind_name_context is set.
+// Resources without bind_name_context only use encryption_context, such as
+// secret versions, which have no name.
+func expandEncryptionContext(d resourceGetter) map[string]*string {
+	name, _ := d.Get("name").(string)
+	return expandParameterEncryptionContext(d, name)
+}
+
+// expandParameterEncryptionContext returns the KMS encryption context of the
+// parameter with the given name, for data sources reading several parameters.
+func expandParameterEncryptionContext(d resourceGetter, name string) map[string]*string {
+	context := make(map[string]*string)
+
+	for k, v := range d.Get("encryption_context").(map[string]interface{}) {
+		context[k] = aws.String(v.(string))
+	}
+
+	if v, ok := d.GetOk("bind_name_context"); ok && v.(bool) {
+		context[ParameterNameContextKey] = aws.String(name)
+	}
+
+	return context
+}
+
+// resolveKmsKeyArn returns the ARN of a KMS key given its ID, ARN, alias name
+// or alias ARN.
+func resolveKmsKeyArn(keyId string, meta interface{}) (string, error) {
+	metadata, err := describeKmsKey(keyId, meta)
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(metadata.Arn), nil
+}
+
+// kmsKeySpec returns the key spec of a KMS key, SYMMETRIC_DEFAULT for
+// symmetric keys.
+func kmsKeySpec(keyId string, meta interface{}) (string, error) {
+	metadata, err := describeKmsKey(keyId, meta)
+	if err != nil {
+		return "", err
+	}
+
+	if keySpec := aws.StringValue(metadata.CustomerMasterKeySpec); keySpec != "" {
+		return keySpec, nil
+	}
+
+	return kms.CustomerMasterKeySpecSymmetricDefault, nil
+}
+
+// describeKmsKey returns the metadata of a KMS key, describing each key ID
+// only once as the same keys are looked up by several diff steps.
+func describeKmsKey(keyId string, meta interface{}) (*kms.KeyMetadata, error) {
+	client := meta.(*AWSClient)
+
+	client.cacheMu.Lock()
+	metadata, ok := client.kmsKeys[keyId]
+	client.cacheMu.Unlock()
+
+	if ok {
+		return metadata, nil
+	}
+
+	resp, err := client.kmsconn.DescribeKey(&kms.DescribeKeyInput{
+		KeyId: aws.String(keyId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing KMS key (%s): %w", keyId, err)
+	}
+
+	client.cacheMu.Lock()
+	if client.kmsKeys == nil {
+		client.kmsKeys = make(map[string]*kms.KeyMetadata)
+	}
+	client.kmsKeys[keyId] = resp.KeyMetadata
+	client.cacheMu.Unlock()
+
+	return resp.KeyMetadata, nil
+}
+
 func kmsDecrypt(decryptInput *kms.DecryptInput, meta interface{}) (*kms.DecryptOutput, error) {
 	kmsconn := meta.(*AWSClient).kmsconn
 	result, err := kmsconn.Decrypt(decryptInput)
@@ -319,3 +1448,48 @@ func kmsDecrypt(decryptInput *kms.DecryptInput, meta interface{}) (*kms.DecryptO
 	}
 	return result, nil
 }
+
+func kmsEncrypt(encryptInput *kms.EncryptInput, meta interface{}) (*kms.EncryptOutput, error) {
+	kmsconn := meta.(*AWSClient).kmsconn
+	result, err := kmsconn.Encrypt(encryptInput)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			return result, errors.New(aerr.Error())
+		} else {
+			return result, errors.New(err.Error())
+		}
+
+	}
+	return result, nil
+}
+
+// encryptValue encrypts plaintext with KMS and returns it in the base64 format
+// accepted by encrypted_value. Values over the kms:Encrypt limit are envelope
+// encrypted under a data key generated with the same key and context.
+func encryptValue(input *kms.EncryptInput, meta interface{}) (string, error) {
+	if len(input.Plaintext) <= kmsEncryptMaxPlaintextSize {
+		result, err := kmsEncrypt(input, meta)
+		if err != nil {
+			return "", fmt.Errorf("Error encrypting with KMS: %s", err)
+		}
+
+		return base64.StdEncoding.EncodeToString(result.CiphertextBlob), nil
+	}
+
+	kmsconn := meta.(*AWSClient).kmsconn
+	dataKey, err := kmsconn.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:             input.KeyId,
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: input.EncryptionContext,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error generating data key with KMS: %s", err)
+	}
+
+	blob, err := sealEnvelope(aws.StringValue(dataKey.KeyId), dataKey.Plaintext, dataKey.CiphertextBlob, input.Plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(blob), nil
+}
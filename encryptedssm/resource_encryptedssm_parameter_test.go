@@ -0,0 +1,1492 @@
+package encryptedssm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const testSsmParameterName = "/encryptedssm/test"
+
+func TestResourceAwsSsmParameter_basic(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encrypted_value", encryptedValue),
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/test"),
+					resource.TestCheckResourceAttr(resourceName, "type", ssm.ParameterTypeSecureString),
+					resource.TestCheckResourceAttr(resourceName, "version", "1"),
+					resource.TestCheckResourceAttrSet(resourceName, "arn"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash_key"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_drift(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+	config := testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.Value = "changed outside terraform"
+						p.Version++
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected drifted value to be restored")
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encrypted_value", encryptedValue),
+					resource.TestCheckResourceAttr(resourceName, "version", "3"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_reencryptedValue(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+	updatedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyN3wp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue),
+			},
+			{
+				// A new ciphertext of the same value is not a change
+				Config:   testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")),
+				PlanOnly: true,
+			},
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, updatedValue),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyN3wp@ss!" {
+							return fmt.Errorf("expected new value to be stored")
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encrypted_value", updatedValue),
+					resource.TestCheckResourceAttr(resourceName, "version", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_encryptedList(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	config := testResourceAwsSsmParameterConfigEncryptedList(
+		kmsconn.testEncrypt(t, "alias/test", nil, "a.example.com"),
+		kmsconn.testEncrypt(t, "alias/test", nil, "b.example.com"),
+	)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "a.example.com,b.example.com" {
+							return fmt.Errorf("expected items to be joined, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "" {
+							return fmt.Errorf("expected no key for a StringList, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "type", ssm.ParameterTypeStringList),
+					resource.TestCheckResourceAttr(resourceName, "key_id", ""),
+					resource.TestCheckResourceAttr(resourceName, "item_hashes.#", "2"),
+					testCheckResourceAttrValueHashes(kmsconn, resourceName, "item_hashes", "a.example.com", "b.example.com"),
+				),
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.Value = "a.example.com,changed.example.com"
+						p.Version++
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "a.example.com,b.example.com" {
+							return fmt.Errorf("expected drifted item to be restored")
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "version", "3"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_stringType(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "not a secret")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				// The fake rejects a KeyId for other types, as SSM does
+				Config: testResourceAwsSsmParameterConfigType(ssm.ParameterTypeString, encryptedValue, "alias/test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "not a secret" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "" {
+							return fmt.Errorf("expected no key for a String, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "type", ssm.ParameterTypeString),
+					resource.TestCheckResourceAttr(resourceName, "key_id", ""),
+				),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigType(ssm.ParameterTypeSecureString, encryptedValue, "alias/test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "type", ssm.ParameterTypeSecureString),
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/test"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_ssmKeyIdDrift(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+	config := testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/test"),
+					resource.TestCheckResourceAttr(resourceName, "ssm_key_id", ""),
+				),
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.KeyId = "alias/other"
+						p.Version++
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key to be restored to alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/test"),
+					resource.TestCheckResourceAttr(resourceName, "ssm_key_id", ""),
+				),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigSsmKeyId(encryptedValue, "alias/other"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.KeyId != "alias/other" {
+							return fmt.Errorf("expected key alias/other, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/other"),
+					resource.TestCheckResourceAttr(resourceName, "ssm_key_id", "alias/other"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_valueHashKeyId(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	testKeyArn, _ := kmsconn.keyArn(aws.String("alias/test"))
+	otherKeyArn := kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	kmsconn.addKey("5678abcd-56ab-78cd-90ef-5678901234ab", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckResourceAttrValueHashKeyArn(kmsconn, resourceName, testKeyArn),
+				),
+			},
+			{
+				// The value hash key is generated anew under the new storage key
+				Config: testResourceAwsSsmParameterConfigSsmKeyId(encryptedValue, "alias/other"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckResourceAttrValueHashKeyArn(kmsconn, resourceName, otherKeyArn),
+					testCheckResourceAttrValueHashes(kmsconn, resourceName, "value_hash", "MyStr0ngp@ss!"),
+				),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigValueHashKeyId(encryptedValue, "alias/other", "alias/test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckResourceAttrValueHashKeyArn(kmsconn, resourceName, testKeyArn),
+					testCheckResourceAttrValueHashes(kmsconn, resourceName, "value_hash", "MyStr0ngp@ss!"),
+				),
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigValueHashKeyId(encryptedValue, "alias/other", "alias/test-rsa"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`value_hash_key_id alias/test-rsa is an asymmetric RSA_2048 KMS key`),
+			},
+		},
+	})
+}
+
+// TestResourceAwsSsmParameter_valueHashKeyIdDiff diffs the resource directly,
+// without Terraform, to check which changes generate a new value hash key.
+func TestResourceAwsSsmParameter_valueHashKeyIdDiff(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	hashKeyBlob, err := generateValueHashKey("alias/test", client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	hashKey, err := decryptValueHashKey(hashKeyBlob, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := &terraform.InstanceState{
+		ID: testSsmParameterName,
+		Attributes: map[string]string{
+			"id":                testSsmParameterName,
+			"name":              testSsmParameterName,
+			"type":              ssm.ParameterTypeSecureString,
+			"tier":              ssm.ParameterTierStandard,
+			"encryption_key":    "alias/test",
+			"encryption_scheme": encryptionSchemeKms,
+			"encrypted_value":   encryptedValue,
+			"value_hash":        valueHash(hashKey, "MyStr0ngp@ss!"),
+			"value_hash_key":    hashKeyBlob,
+		},
+	}
+
+	cases := []struct {
+		Config           map[string]interface{}
+		ExpectNewHashKey bool
+	}{
+		{
+			Config: map[string]interface{}{},
+		},
+		{
+			Config:           map[string]interface{}{"ssm_key_id": "alias/other"},
+			ExpectNewHashKey: true,
+		},
+		{
+			Config:           map[string]interface{}{"value_hash_key_id": "alias/other"},
+			ExpectNewHashKey: true,
+		},
+		{
+			// The key the value hash key was generated under does not change
+			Config: map[string]interface{}{"value_hash_key_id": "alias/test"},
+		},
+		{
+			Config: map[string]interface{}{"ssm_key_id": "alias/other", "value_hash_key_id": "alias/test"},
+		},
+	}
+
+	for _, tc := range cases {
+		config := map[string]interface{}{
+			"name":            testSsmParameterName,
+			"type":            ssm.ParameterTypeSecureString,
+			"tier":            ssm.ParameterTierStandard,
+			"encryption_key":  "alias/test",
+			"encrypted_value": encryptedValue,
+		}
+		for k, v := range tc.Config {
+			config[k] = v
+		}
+
+		r := resourceAwsSsmParameter(func() interface{} { return client })
+		diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), client)
+		if err != nil {
+			t.Fatalf("err with %v: %s", tc.Config, err)
+		}
+
+		var newHashKey bool
+		if diff != nil && diff.Attributes["value_hash_key"] != nil {
+			newHashKey = diff.Attributes["value_hash_key"].NewComputed
+		}
+
+		if newHashKey != tc.ExpectNewHashKey {
+			t.Fatalf("expected a new value hash key with %v to be %t", tc.Config, tc.ExpectNewHashKey)
+		}
+	}
+}
+
+func TestResourceAwsSsmParameter_encryptionContext(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", map[string]string{
+		"environment": "prod",
+	}, "MyStr0ngp@ss!")
+	boundValue := kmsconn.testEncrypt(t, "alias/test", map[string]string{
+		"environment":           "prod",
+		ParameterNameContextKey: testSsmParameterName,
+	}, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigEncryptionContext(encryptedValue, "staging", false),
+				ExpectError: regexp.MustCompile(`Error decrypting with KMS`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigEncryptionContext(encryptedValue, "prod", false),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encryption_context.environment", "prod"),
+					resource.TestCheckResourceAttr(resourceName, "bind_name_context", "false"),
+				),
+			},
+			{
+				// A value not bound to the parameter name is rejected
+				Config:      testResourceAwsSsmParameterConfigEncryptionContext(encryptedValue, "prod", true),
+				ExpectError: regexp.MustCompile(`Error decrypting with KMS`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigEncryptionContext(boundValue, "prod", true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "encrypted_value", boundValue),
+					resource.TestCheckResourceAttr(resourceName, "bind_name_context", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_tags(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfigTags(encryptedValue, "Name", "test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Tags["Name"] != "test" || p.Tags["Team"] != "platform" {
+							return fmt.Errorf("expected resource and default tags, got %v", p.Tags)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "tags.%", "1"),
+					resource.TestCheckResourceAttr(resourceName, "tags.Name", "test"),
+					resource.TestCheckResourceAttr(resourceName, "tags_all.%", "2"),
+					resource.TestCheckResourceAttr(resourceName, "tags_all.Team", "platform"),
+				),
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.Tags["CostCenter"] = "1234"
+					})
+				},
+				Config: testResourceAwsSsmParameterConfigTags(encryptedValue, "Name", "updated"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Tags["Name"] != "updated" {
+							return fmt.Errorf("expected Name tag to be updated, got %v", p.Tags)
+						}
+						if p.Tags["CostCenter"] != "1234" {
+							return fmt.Errorf("expected ignored tag to be left in place, got %v", p.Tags)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "tags.%", "1"),
+					resource.TestCheckResourceAttr(resourceName, "tags_all.%", "2"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_tierDowngrade(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierAdvanced, encryptedValue),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "tier", ssm.ParameterTierAdvanced),
+				),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Tier != ssm.ParameterTierStandard {
+							return fmt.Errorf("expected tier %s, got %s", ssm.ParameterTierStandard, p.Tier)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "tier", ssm.ParameterTierStandard),
+					// Recreated rather than updated
+					resource.TestCheckResourceAttr(resourceName, "version", "1"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_import(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+	kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfig(ssm.ParameterTierStandard, encryptedValue),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateId:     testSsmParameterName + ",alias/test",
+				ImportStateVerify: true,
+				// The value is encrypted again and a new hash key is generated on import
+				ImportStateVerifyIgnore: []string{"encrypted_value", "overwrite", "value_hash", "value_hash_key"},
+				ImportStateCheck: func(s []*terraform.InstanceState) error {
+					if len(s) != 1 {
+						return fmt.Errorf("expected 1 state, got %d", len(s))
+					}
+					if plaintext, err := kmsconn.decrypt(s[0].Attributes["encrypted_value"]); err != nil || plaintext != "MyStr0ngp@ss!" {
+						return fmt.Errorf("expected imported encrypted_value to decrypt to the parameter value")
+					}
+					return nil
+				},
+			},
+			{
+				ResourceName:  resourceName,
+				ImportState:   true,
+				ImportStateId: testSsmParameterName + ",alias/test-rsa",
+				ExpectError:   regexp.MustCompile(`KMS key alias/test-rsa is an asymmetric RSA_2048 key`),
+			},
+			{
+				ResourceName:  resourceName,
+				ImportState:   true,
+				ImportStateId: testSsmParameterName,
+				ExpectError:   regexp.MustCompile(`expected NAME,KMS-KEY-ID`),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_importArgumentsDiff(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	testPutFakeSsmParameter(t, ssmconn, testSsmParameterName, ssm.ParameterTypeSecureString, "MyStr0ngp@ss!")
+
+	cases := []struct {
+		Name   string
+		Args   ParameterImportArguments
+		Config map[string]interface{}
+	}{
+		{
+			Name: "no arguments",
+			Config: map[string]interface{}{
+				"encrypted_value": kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!"),
+				"ssm_key_id":      "alias/aws/ssm",
+			},
+		},
+		{
+			// As written by migrate -bind-name -context for a parameter
+			// stored with the AWS managed key
+			Name: "context and value hash key",
+			Args: ParameterImportArguments{
+				BindNameContext:   true,
+				EncryptionContext: map[string]string{"environment": "prod"},
+				ValueHashKeyId:    "alias/test",
+			},
+			Config: map[string]interface{}{
+				"encrypted_value": kmsconn.testEncrypt(t, "alias/test", map[string]string{
+					"environment":           "prod",
+					ParameterNameContextKey: testSsmParameterName,
+				}, "MyStr0ngp@ss!"),
+				"bind_name_context":  true,
+				"encryption_context": map[string]interface{}{"environment": "prod"},
+				"ssm_key_id":         "alias/aws/ssm",
+				"value_hash_key_id":  "alias/test",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			id, err := ParameterImportId(testSsmParameterName, "alias/test", tc.Args)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			r := resourceAwsSsmParameter(func() interface{} { return client })
+
+			imported, err := r.Importer.State(r.Data(&terraform.InstanceState{ID: id}), client)
+			if err != nil {
+				t.Fatalf("error importing %s: %s", id, err)
+			}
+
+			if err := r.Read(imported[0], client); err != nil {
+				t.Fatalf("error reading %s: %s", id, err)
+			}
+
+			config := map[string]interface{}{
+				"name":           testSsmParameterName,
+				"type":           ssm.ParameterTypeSecureString,
+				"encryption_key": "alias/test",
+			}
+			for k, v := range tc.Config {
+				config[k] = v
+			}
+
+			diff, err := r.Diff(context.Background(), imported[0].State(), terraform.NewResourceConfigRaw(config), client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if diff != nil && !diff.Empty() {
+				t.Fatalf("expected the imported parameter to plan clean, got %#v", diff.Attributes)
+			}
+		})
+	}
+
+	r := resourceAwsSsmParameter(func() interface{} { return client })
+	id := testSsmParameterName + `,alias/test,{"bind_name":true}`
+
+	if _, err := r.Importer.State(r.Data(&terraform.InstanceState{ID: id}), client); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Fatalf("expected an unknown argument error, got %v", err)
+	}
+}
+
+func TestResourceAwsSsmParameter_allowedPattern(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigAllowedPattern(encryptedValue, `^\d+$`),
+				ExpectError: regexp.MustCompile(`decrypted value does not match allowed_pattern`),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_dataTypeEc2Image(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigDataType(kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!"), ssmParameterDataTypeEc2Image),
+				ExpectError: regexp.MustCompile(`decrypted value is not an AMI ID`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigDataType(kmsconn.testEncrypt(t, "alias/test", nil, "ami-0123456789abcdef0"), ssmParameterDataTypeEc2Image),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "data_type", ssmParameterDataTypeEc2Image),
+				),
+			},
+		},
+	})
+}
+
+// TestResourceAwsSsmParameter_valueValidationDiagnostic diffs the resource
+// directly, without Terraform, to check the errors of the value validation
+// never include the decrypted value.
+func TestResourceAwsSsmParameter_valueValidationDiagnostic(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	plaintext := "MyStr0ngp@ss!"
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, plaintext)
+
+	cases := []struct {
+		Config        map[string]interface{}
+		ExpectedError *regexp.Regexp
+	}{
+		{
+			Config:        map[string]interface{}{"allowed_pattern": `^\d+$`},
+			ExpectedError: regexp.MustCompile(`decrypted value does not match allowed_pattern`),
+		},
+		{
+			Config:        map[string]interface{}{"data_type": ssmParameterDataTypeEc2Image},
+			ExpectedError: regexp.MustCompile(`decrypted value is not an AMI ID`),
+		},
+	}
+
+	for _, tc := range cases {
+		config := map[string]interface{}{
+			"name":            testSsmParameterName,
+			"type":            ssm.ParameterTypeSecureString,
+			"encryption_key":  "alias/test",
+			"encrypted_value": encryptedValue,
+		}
+		for k, v := range tc.Config {
+			config[k] = v
+		}
+
+		r := resourceAwsSsmParameter(func() interface{} { return client })
+		_, err := r.Diff(context.Background(), nil, terraform.NewResourceConfigRaw(config), client)
+		if err == nil {
+			t.Fatalf("expected an error with %v", tc.Config)
+		}
+
+		if !tc.ExpectedError.MatchString(err.Error()) {
+			t.Fatalf("expected an error matching %s, got: %s", tc.ExpectedError, err)
+		}
+
+		if strings.Contains(err.Error(), plaintext) {
+			t.Fatalf("error includes the decrypted value: %s", err)
+		}
+	}
+}
+
+func TestResourceAwsSsmParameter_ciphertextValidation(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	otherKeyArn := kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	largeValue := strings.Repeat("x", kmsEncryptMaxPlaintextSize+1)
+
+	otherEnvelope, err := encryptValue(&kms.EncryptInput{
+		KeyId:     aws.String("alias/other"),
+		Plaintext: []byte(largeValue),
+	}, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// An envelope whose data is sealed with another data key than the one
+	// encrypted with KMS only fails once decrypted
+	dataKey, err := kmsconn.GenerateDataKey(&kms.GenerateDataKeyInput{KeyId: aws.String("alias/test")})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	corruptEnvelope, err := sealEnvelope("alias/test", make([]byte, 32), dataKey.CiphertextBlob, []byte(largeValue))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigVerifyDecryption("not base64!", false),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encrypted_value is not valid base64`),
+			},
+			{
+				// The key of a KMS ciphertext blob is only checked by decrypting it
+				Config:             testResourceAwsSsmParameterConfigVerifyDecryption(kmsconn.testEncrypt(t, "alias/other", nil, "MyStr0ngp@ss!"), false),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigVerifyDecryption(kmsconn.testEncrypt(t, "alias/other", nil, "MyStr0ngp@ss!"), true),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encrypted_value was encrypted with KMS key ` + regexp.QuoteMeta(otherKeyArn) + `, not encryption_key`),
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigVerifyDecryption(otherEnvelope, false),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encrypted_value was encrypted with KMS key ` + regexp.QuoteMeta(otherKeyArn) + `, not encryption_key`),
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigVerifyDecryption(kmsconn.testEncrypt(t, "alias/test", map[string]string{"environment": "prod"}, "MyStr0ngp@ss!"), true),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encrypted_value could not be decrypted`),
+			},
+			{
+				Config:             testResourceAwsSsmParameterConfigVerifyDecryption(base64.StdEncoding.EncodeToString(corruptEnvelope), false),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigVerifyDecryption(base64.StdEncoding.EncodeToString(corruptEnvelope), true),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encrypted_value could not be decrypted: error decrypting envelope`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigVerifyDecryption(kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!"), true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("encryptedssm_parameter.test", "verify_decryption", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_ageScheme(t *testing.T) {
+	client, ssmconn, _ := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	encryptedValue := base64.StdEncoding.EncodeToString([]byte(testAgeEncrypt(t, identity.Recipient(), false, "MyStr0ngp@ss!")))
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfigAgeScheme(identity.String(), encryptedValue),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encryption_scheme", "age"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_vaultScheme(t *testing.T) {
+	client, ssmconn, _ := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	server := newTestVaultServer(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfigVaultScheme(server.URL, `
+  encryption_key    = "other"
+  encryption_scheme = "vault"
+  encrypted_value   = "vault:v1:b3RoZXI="
+  ssm_key_id        = "alias/test"`),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "0ther" {
+							return fmt.Errorf("expected the value decrypted with Transit key other to be stored, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+				),
+			},
+			{
+				// Without encryption_key the provider key_name is used
+				Config: testResourceAwsSsmParameterConfigVaultScheme(server.URL, `
+  encryption_scheme = "vault"
+  encrypted_value   = "vault:v1:c2VjcmV0"
+  ssm_key_id        = "alias/test"`),
+				Check: testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+					if p.Value != "MyStr0ngp@ss!" {
+						return fmt.Errorf("expected the value decrypted with Transit key test to be stored, got %d bytes", len(p.Value))
+					}
+					return nil
+				}),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_schemeRequiredArguments(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+	server := newTestVaultServer(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSsmParameterConfigVaultScheme(server.URL, `
+  encryption_key    = "test"
+  encryption_scheme = "vault"
+  encrypted_value   = "vault:v1:c2VjcmV0"`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`ssm_key_id is required with encryption_scheme "vault"`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigVaultScheme(server.URL, `
+  encrypted_value = "AQICAHh..."`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encryption_key is required with encryption_scheme "kms"`),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_encryptedFields(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedPassword := kmsconn.testEncrypt(t, "alias/test", nil, "p<a>ss&word")
+	config := testResourceAwsSsmParameterConfigEncryptedFields(encryptedPassword)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != `{"password":"p<a>ss&word","user":"app"}` {
+							return fmt.Errorf("expected canonical JSON to be stored, got %d bytes", len(p.Value))
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "field_hashes.%", "2"),
+					resource.TestCheckResourceAttrSet(resourceName, "field_hashes.password"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+				),
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.Value = `{"password":"changed outside terraform","user":"app"}`
+						p.Version++
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != `{"password":"p<a>ss&word","user":"app"}` {
+							return fmt.Errorf("expected drifted field to be restored")
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_valueTemplate(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	encryptedPassword := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+	valueTemplate := "postgres://app:{{ password }}@db.internal:5432/app"
+	config := testResourceAwsSsmParameterConfigValueTemplate(valueTemplate, encryptedPassword)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigValueTemplate("postgres://app:{{ passwd }}@db.internal:5432/app", encryptedPassword),
+				ExpectError: regexp.MustCompile(`placeholder "passwd" has no encrypted_values entry`),
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "postgres://app:MyStr0ngp@ss!@db.internal:5432/app" {
+							return fmt.Errorf("expected rendered template to be stored, got %d bytes", len(p.Value))
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "value_template", valueTemplate),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+				),
+			},
+			{
+				PreConfig: func() {
+					ssmconn.update(testSsmParameterName, func(p *fakeSSMParameter) {
+						p.Value = "postgres://app:MyStr0ngp@ss!@db.other:5432/app"
+						p.Version++
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_asymmetricKeyRequiresSsmKeyId(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigAsymmetric(base64.StdEncoding.EncodeToString([]byte("ciphertext"))),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`ssm_key_id is required with encryption_algorithm "RSAES_OAEP_SHA_256"`),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSsmParameter_asymmetricKey(t *testing.T) {
+	client, ssmconn, kmsconn := newTestAWSClient()
+	resourceName := "encryptedssm_parameter.test"
+	kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+
+	output, err := kmsconn.Encrypt(&kms.EncryptInput{
+		KeyId:               aws.String("alias/test-rsa"),
+		EncryptionAlgorithm: aws.String(kms.EncryptionAlgorithmSpecRsaesOaepSha256),
+		Plaintext:           []byte("MyStr0ngp@ss!"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	encryptedValue := base64.StdEncoding.EncodeToString(output.CiphertextBlob)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSsmParameterDestroy(ssmconn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSsmParameterConfigType(ssm.ParameterTypeSecureString, encryptedValue, "alias/test-rsa"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`encryption_key alias/test-rsa is an asymmetric RSA_2048 KMS key, set ssm_key_id to a symmetric KMS key`),
+			},
+			{
+				Config:      testResourceAwsSsmParameterConfigAsymmetricSsmKeyId(encryptedValue, "alias/test-rsa"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`ssm_key_id alias/test-rsa is an asymmetric RSA_2048 KMS key`),
+			},
+			{
+				Config: testResourceAwsSsmParameterConfigAsymmetricSsmKeyId(encryptedValue, "alias/test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSsmParameter(ssmconn, func(p *fakeSSMParameter) error {
+						if p.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(p.Value))
+						}
+						if p.KeyId != "alias/test" {
+							return fmt.Errorf("expected key alias/test, got %s", p.KeyId)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "key_id", "alias/test"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash_key"),
+				),
+			},
+		},
+	})
+}
+
+func testCheckFakeSsmParameter(ssmconn *fakeSSM, f func(*fakeSSMParameter) error) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		p := ssmconn.parameter(testSsmParameterName)
+		if p == nil {
+			return fmt.Errorf("SSM Parameter (%s) not found", testSsmParameterName)
+		}
+
+		return f(p)
+	}
+}
+
+func testCheckFakeSsmParameterDestroy(ssmconn *fakeSSM) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		if p := ssmconn.parameter(testSsmParameterName); p != nil {
+			return fmt.Errorf("SSM Parameter (%s) still exists", testSsmParameterName)
+		}
+
+		return nil
+	}
+}
+
+func testResourceAwsSsmParameterConfig(tier, encryptedValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = "SecureString"
+  tier            = %[3]q
+  encryption_key  = "alias/test"
+  encrypted_value = %[4]q
+}
+`, testRegion, testSsmParameterName, tier, encryptedValue)
+}
+
+// testCheckResourceAttrValueHashes checks a list attribute holds the value
+// hashes of values under the resource value_hash_key.
+func testCheckResourceAttrValueHashes(kmsconn *fakeKMS, name, key string, values ...string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("not found: %s", name)
+		}
+
+		hashKey, err := kmsconn.decrypt(rs.Primary.Attributes["value_hash_key"])
+		if err != nil {
+			return fmt.Errorf("%s: error decrypting value_hash_key: %s", name, err)
+		}
+
+		for i, v := range values {
+			k := fmt.Sprintf("%s.%d", key, i)
+			if rs.Primary.Attributes[k] != valueHash([]byte(hashKey), v) {
+				return fmt.Errorf("%s: expected %s to be the hash of item %d", name, k, i)
+			}
+		}
+
+		return nil
+	}
+}
+
+func testResourceAwsSsmParameterConfigEncryptedList(encryptedItems ...string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name           = %[2]q
+  type           = "StringList"
+  encryption_key = "alias/test"
+  encrypted_list = %[3]s
+}
+`, testRegion, testSsmParameterName, testHclStringList(encryptedItems))
+}
+
+func testResourceAwsSsmParameterConfigType(parameterType, encryptedValue, encryptionKey string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = %[3]q
+  encryption_key  = %[4]q
+  encrypted_value = %[5]q
+}
+`, testRegion, testSsmParameterName, parameterType, encryptionKey, encryptedValue)
+}
+
+func testResourceAwsSsmParameterConfigSsmKeyId(encryptedValue, ssmKeyId string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  ssm_key_id      = %[3]q
+  encrypted_value = %[4]q
+}
+`, testRegion, testSsmParameterName, ssmKeyId, encryptedValue)
+}
+
+func testResourceAwsSsmParameterConfigValueHashKeyId(encryptedValue, ssmKeyId, valueHashKeyId string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name              = %[2]q
+  type              = "SecureString"
+  encryption_key    = "alias/test"
+  ssm_key_id        = %[3]q
+  value_hash_key_id = %[4]q
+  encrypted_value   = %[5]q
+}
+`, testRegion, testSsmParameterName, ssmKeyId, valueHashKeyId, encryptedValue)
+}
+
+// testCheckResourceAttrValueHashKeyArn checks the resource value_hash_key was
+// generated under the KMS key with the given ARN.
+func testCheckResourceAttrValueHashKeyArn(kmsconn *fakeKMS, name, keyArn string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("not found: %s", name)
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(rs.Primary.Attributes["value_hash_key"])
+		if err != nil {
+			return fmt.Errorf("%s: error decoding value_hash_key: %s", name, err)
+		}
+
+		output, err := kmsconn.Decrypt(&kms.DecryptInput{CiphertextBlob: blob})
+		if err != nil {
+			return fmt.Errorf("%s: error decrypting value_hash_key: %s", name, err)
+		}
+
+		if aws.StringValue(output.KeyId) != keyArn {
+			return fmt.Errorf("%s: expected value_hash_key to be generated under %s, got %s", name, keyArn, aws.StringValue(output.KeyId))
+		}
+
+		return nil
+	}
+}
+
+func testResourceAwsSsmParameterConfigEncryptionContext(encryptedValue, environment string, bindNameContext bool) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name              = %[2]q
+  type              = "SecureString"
+  encryption_key    = "alias/test"
+  encrypted_value   = %[3]q
+  bind_name_context = %[5]t
+
+  encryption_context = {
+    environment = %[4]q
+  }
+}
+`, testRegion, testSsmParameterName, encryptedValue, environment, bindNameContext)
+}
+
+// testHclStringList returns values as an HCL list expression.
+func testHclStringList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func testResourceAwsSsmParameterConfigTags(encryptedValue, tagKey, tagValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+
+  default_tags {
+    tags = {
+      Team = "platform"
+    }
+  }
+
+  ignore_tags {
+    keys = ["CostCenter"]
+  }
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  encrypted_value = %[3]q
+
+  tags = {
+    %[4]s = %[5]q
+  }
+}
+`, testRegion, testSsmParameterName, encryptedValue, tagKey, tagValue)
+}
+
+func testResourceAwsSsmParameterConfigAllowedPattern(encryptedValue, allowedPattern string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  encrypted_value = %[3]q
+  allowed_pattern = %[4]q
+}
+`, testRegion, testSsmParameterName, encryptedValue, allowedPattern)
+}
+
+func testResourceAwsSsmParameterConfigDataType(encryptedValue, dataType string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name            = %[2]q
+  type            = "SecureString"
+  encryption_key  = "alias/test"
+  encrypted_value = %[3]q
+  data_type       = %[4]q
+}
+`, testRegion, testSsmParameterName, encryptedValue, dataType)
+}
+
+func testResourceAwsSsmParameterConfigVerifyDecryption(encryptedValue string, verifyDecryption bool) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name              = %[2]q
+  type              = "SecureString"
+  encryption_key    = "alias/test"
+  encrypted_value   = %[3]q
+  verify_decryption = %[4]t
+}
+`, testRegion, testSsmParameterName, encryptedValue, verifyDecryption)
+}
+
+func testResourceAwsSsmParameterConfigAgeScheme(identity, encryptedValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+
+  age {
+    identity = %[2]q
+  }
+}
+
+resource "encryptedssm_parameter" "test" {
+  name              = %[3]q
+  type              = "SecureString"
+  encryption_key    = "alias/test"
+  encryption_scheme = "age"
+  encrypted_value   = %[4]q
+}
+`, testRegion, identity, testSsmParameterName, encryptedValue)
+}
+
+func testResourceAwsSsmParameterConfigVaultScheme(address, arguments string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+
+  vault {
+    address  = %[2]q
+    token    = "root"
+    key_name = "test"
+  }
+}
+
+resource "encryptedssm_parameter" "test" {
+  name = %[3]q
+  type = "SecureString"
+%[4]s
+}
+`, testRegion, address, testSsmParameterName, arguments)
+}
+
+func testResourceAwsSsmParameterConfigEncryptedFields(encryptedPassword string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name           = %[2]q
+  type           = "SecureString"
+  encryption_key = "alias/test"
+
+  encrypted_fields = {
+    password = %[3]q
+  }
+
+  plaintext_fields = {
+    user = "app"
+  }
+}
+`, testRegion, testSsmParameterName, encryptedPassword)
+}
+
+func testResourceAwsSsmParameterConfigValueTemplate(valueTemplate, encryptedPassword string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name           = %[2]q
+  type           = "SecureString"
+  encryption_key = "alias/test"
+  value_template = %[3]q
+
+  encrypted_values = {
+    password = %[4]q
+  }
+}
+`, testRegion, testSsmParameterName, valueTemplate, encryptedPassword)
+}
+
+func testResourceAwsSsmParameterConfigAsymmetric(encryptedValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name                 = %[2]q
+  type                 = "SecureString"
+  encryption_key       = "alias/test"
+  encryption_algorithm = "RSAES_OAEP_SHA_256"
+  encrypted_value      = %[3]q
+}
+`, testRegion, testSsmParameterName, encryptedValue)
+}
+
+func testResourceAwsSsmParameterConfigAsymmetricSsmKeyId(encryptedValue, ssmKeyId string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_parameter" "test" {
+  name                 = %[2]q
+  type                 = "SecureString"
+  encryption_key       = "alias/test-rsa"
+  encryption_algorithm = "RSAES_OAEP_SHA_256"
+  ssm_key_id           = %[3]q
+  encrypted_value      = %[4]q
+}
+`, testRegion, testSsmParameterName, ssmKeyId, encryptedValue)
+}
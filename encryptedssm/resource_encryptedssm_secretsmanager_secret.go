@@ -0,0 +1,197 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+func resourceAwsSecretsManagerSecret() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsSecretsManagerSecretCreate,
+		Read:   resourceAwsSecretsManagerSecretRead,
+		Update: resourceAwsSecretsManagerSecretUpdate,
+		Delete: resourceAwsSecretsManagerSecretDelete,
+		Importer: &schema.ResourceImporter{
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				d.Set("recovery_window_in_days", 30)
+				return schema.ImportStatePassthrough(d, meta)
+			},
+		},
+
+		Schema: map[string]*schema.Schema{
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kms_key_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"recovery_window_in_days": {
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  30,
+				ValidateFunc: validation.Any(
+					validation.IntBetween(7, 30),
+					validation.IntInSlice([]int{0}),
+				),
+			},
+			"tags":     tagsSchema(),
+			"tags_all": tagsSchemaComputed(),
+		},
+
+		CustomizeDiff: SetTagsDiff,
+	}
+}
+
+func resourceAwsSecretsManagerSecretCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+	defaultTagsConfig := meta.(*AWSClient).DefaultTagsConfig
+	tags := defaultTagsConfig.MergeTags(New(d.Get("tags").(map[string]interface{})))
+
+	name := d.Get("name").(string)
+
+	input := &secretsmanager.CreateSecretInput{
+		Name:        aws.String(name),
+		Description: aws.String(d.Get("description").(string)),
+	}
+
+	if len(tags) > 0 {
+		input.Tags = tags.IgnoreAws().SecretsmanagerTags()
+	}
+
+	if v, ok := d.GetOk("kms_key_id"); ok {
+		input.KmsKeyId = aws.String(v.(string))
+	}
+
+	log.Printf("[DEBUG] Creating Secrets Manager Secret: %s", name)
+
+	output, err := conn.CreateSecret(input)
+	if err != nil {
+		return fmt.Errorf("error creating Secrets Manager Secret (%s): %s", name, err)
+	}
+
+	d.SetId(aws.StringValue(output.ARN))
+
+	return resourceAwsSecretsManagerSecretRead(d, meta)
+}
+
+func resourceAwsSecretsManagerSecretRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+	defaultTagsConfig := meta.(*AWSClient).DefaultTagsConfig
+	ignoreTagsConfig := meta.(*AWSClient).IgnoreTagsConfig
+
+	log.Printf("[DEBUG] Reading Secrets Manager Secret: %s", d.Id())
+
+	output, err := conn.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(d.Id()),
+	})
+
+	if isAWSErr(err, secretsmanager.ErrCodeResourceNotFoundException, "") {
+		log.Printf("[WARN] Secrets Manager Secret (%s) not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error reading Secrets Manager Secret (%s): %w", d.Id(), err)
+	}
+
+	if output.DeletedDate != nil {
+		log.Printf("[WARN] Secrets Manager Secret (%s) is scheduled for deletion, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("arn", output.ARN)
+	d.Set("description", output.Description)
+	d.Set("kms_key_id", output.KmsKeyId)
+	d.Set("name", output.Name)
+
+	tags := SecretsmanagerKeyValueTags(output.Tags).IgnoreAws().IgnoreConfig(ignoreTagsConfig)
+
+	//lintignore:AWSR002
+	if err := d.Set("tags", tags.RemoveDefaultConfig(defaultTagsConfig).Map()); err != nil {
+		return fmt.Errorf("error setting tags: %s", err)
+	}
+
+	if err := d.Set("tags_all", tags.Map()); err != nil {
+		return fmt.Errorf("error setting tags_all: %s", err)
+	}
+
+	return nil
+}
+
+func resourceAwsSecretsManagerSecretUpdate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+
+	if d.HasChanges("description", "kms_key_id") {
+		input := &secretsmanager.UpdateSecretInput{
+			SecretId:    aws.String(d.Id()),
+			Description: aws.String(d.Get("description").(string)),
+		}
+
+		if v, ok := d.GetOk("kms_key_id"); ok {
+			input.KmsKeyId = aws.String(v.(string))
+		}
+
+		log.Printf("[DEBUG] Updating Secrets Manager Secret: %s", d.Id())
+
+		if _, err := conn.UpdateSecret(input); err != nil {
+			return fmt.Errorf("error updating Secrets Manager Secret (%s): %s", d.Id(), err)
+		}
+	}
+
+	if d.HasChange("tags_all") {
+		o, n := d.GetChange("tags_all")
+
+		if err := SecretsmanagerUpdateTags(conn, d.Id(), o, n, meta.(*AWSClient).IgnoreTagsConfig); err != nil {
+			return fmt.Errorf("error updating Secrets Manager Secret (%s) tags: %s", d.Id(), err)
+		}
+	}
+
+	return resourceAwsSecretsManagerSecretRead(d, meta)
+}
+
+func resourceAwsSecretsManagerSecretDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+
+	input := &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(d.Id()),
+	}
+
+	recoveryWindowInDays := d.Get("recovery_window_in_days").(int)
+	if recoveryWindowInDays == 0 {
+		input.ForceDeleteWithoutRecovery = aws.Bool(true)
+	} else {
+		input.RecoveryWindowInDays = aws.Int64(int64(recoveryWindowInDays))
+	}
+
+	log.Printf("[INFO] Deleting Secrets Manager Secret: %s", d.Id())
+
+	_, err := conn.DeleteSecret(input)
+
+	if isAWSErr(err, secretsmanager.ErrCodeResourceNotFoundException, "") {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error deleting Secrets Manager Secret (%s): %s", d.Id(), err)
+	}
+
+	return nil
+}
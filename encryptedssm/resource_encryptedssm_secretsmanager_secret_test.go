@@ -0,0 +1,100 @@
+package encryptedssm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const testSecretsManagerSecretName = "encryptedssm/test"
+
+func TestResourceAwsSecretsManagerSecret_basic(t *testing.T) {
+	client, _, _ := newTestAWSClient()
+	conn := client.secretsmanagerconn.(*fakeSecretsManager)
+	resourceName := "encryptedssm_secretsmanager_secret.test"
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSecretsManagerSecretDestroy(conn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSecretsManagerSecretConfig("test", "Name", "test"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSecretsManagerSecret(conn, func(s *fakeSecretsManagerSecret) error {
+						if s.Tags["Name"] != "test" || s.Tags["Team"] != "platform" {
+							return fmt.Errorf("expected resource and default tags, got %v", s.Tags)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttrSet(resourceName, "arn"),
+					resource.TestCheckResourceAttr(resourceName, "description", "test"),
+					resource.TestCheckResourceAttr(resourceName, "tags.%", "1"),
+					resource.TestCheckResourceAttr(resourceName, "tags_all.%", "2"),
+				),
+			},
+			{
+				Config: testResourceAwsSecretsManagerSecretConfig("updated", "Name", "updated"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSecretsManagerSecret(conn, func(s *fakeSecretsManagerSecret) error {
+						if s.Description != "updated" {
+							return fmt.Errorf("expected description to be updated, got %s", s.Description)
+						}
+						if s.Tags["Name"] != "updated" {
+							return fmt.Errorf("expected Name tag to be updated, got %v", s.Tags)
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "description", "updated"),
+				),
+			},
+		},
+	})
+}
+
+func testCheckFakeSecretsManagerSecret(conn *fakeSecretsManager, f func(*fakeSecretsManagerSecret) error) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		s := conn.lookup(testSecretsManagerSecretName)
+		if s == nil {
+			return fmt.Errorf("Secrets Manager Secret (%s) not found", testSecretsManagerSecretName)
+		}
+
+		return f(s)
+	}
+}
+
+func testCheckFakeSecretsManagerSecretDestroy(conn *fakeSecretsManager) resource.TestCheckFunc {
+	return func(_ *terraform.State) error {
+		if s := conn.lookup(testSecretsManagerSecretName); s != nil {
+			return fmt.Errorf("Secrets Manager Secret (%s) still exists", testSecretsManagerSecretName)
+		}
+
+		return nil
+	}
+}
+
+func testResourceAwsSecretsManagerSecretConfig(description, tagKey, tagValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+
+  default_tags {
+    tags = {
+      Team = "platform"
+    }
+  }
+}
+
+resource "encryptedssm_secretsmanager_secret" "test" {
+  name                    = %[2]q
+  description             = %[3]q
+  recovery_window_in_days = 0
+
+  tags = {
+    %[4]s = %[5]q
+  }
+}
+`, testRegion, testSecretsManagerSecretName, description, tagKey, tagValue)
+}
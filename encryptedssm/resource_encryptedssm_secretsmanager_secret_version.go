@@ -0,0 +1,427 @@
+package encryptedssm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+const (
+	// Staging label of the version Secrets Manager returns by default.
+	secretsManagerVersionStageCurrent = "AWSCURRENT"
+
+	// Key Secrets Manager encrypts secrets without a kms_key_id with.
+	secretsManagerDefaultKmsKeyId = "alias/aws/secretsmanager"
+)
+
+var (
+	// Arguments the decrypted secret value depends on.
+	secretsManagerSecretVersionValueAttributes = []string{
+		"encrypted_value",
+		"encryption_key",
+		"encryption_scheme",
+		"encryption_algorithm",
+		"encryption_context",
+	}
+
+	// Arguments besides the ciphertext the secret value is decrypted with.
+	secretsManagerSecretVersionDecryptionAttributes = []string{
+		"encryption_key",
+		"encryption_scheme",
+		"encryption_algorithm",
+		"encryption_context",
+	}
+)
+
+func resourceAwsSecretsManagerSecretVersion(providerMeta func() interface{}) *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsSecretsManagerSecretVersionPut,
+		Read:   resourceAwsSecretsManagerSecretVersionRead,
+		Update: resourceAwsSecretsManagerSecretVersionPut,
+		Delete: resourceAwsSecretsManagerSecretVersionDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceAwsSecretsManagerSecretVersionImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"secret_id": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"encrypted_value": {
+				Type:             schema.TypeString,
+				Required:         true,
+				DiffSuppressFunc: suppressEquivalentCiphertextDiff(providerMeta, secretsManagerSecretVersionDecryptionAttributes),
+			},
+			"encryption_key": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"encryption_scheme": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      encryptionSchemeKms,
+				ValidateFunc: validation.StringInSlice(encryptionSchemes, false),
+			},
+			"encryption_algorithm": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					kms.EncryptionAlgorithmSpecSymmetricDefault,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha1,
+					kms.EncryptionAlgorithmSpecRsaesOaepSha256,
+				}, false),
+			},
+			"encryption_context": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"value_hash_key_id": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"version_id": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"value_hash": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"value_hash_key": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+
+		CustomizeDiff: customdiff.All(
+			resourceAwsSecretsManagerSecretVersionCustomizeDiffScheme,
+			resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHashKeyId,
+			resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHash,
+		),
+	}
+}
+
+// resourceAwsSecretsManagerSecretVersionCustomizeDiffScheme requires
+// encryption_key except with the vault encryption scheme and rejects the KMS
+// only arguments for encryption schemes decrypted with the provider
+// configuration.
+func resourceAwsSecretsManagerSecretVersionCustomizeDiffScheme(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	scheme := diff.Get("encryption_scheme").(string)
+	if !diff.NewValueKnown("encryption_scheme") {
+		return nil
+	}
+
+	secretId := diff.Get("secret_id").(string)
+
+	if _, ok := diff.GetOk("encryption_key"); !ok && diff.NewValueKnown("encryption_key") && scheme != encryptionSchemeVault {
+		return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: encryption_key is required with encryption_scheme %q", secretId, scheme)
+	}
+
+	if scheme == encryptionSchemeKms {
+		return nil
+	}
+
+	for _, k := range []string{"encryption_algorithm", "encryption_context"} {
+		if _, ok := diff.GetOk(k); ok {
+			return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: %s is not supported with encryption_scheme %q", secretId, k, scheme)
+		}
+	}
+
+	return nil
+}
+
+// resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHashKeyId rejects
+// an asymmetric value_hash_key_id during plan, as the value hash key is a data
+// key generated under it, and marks the value hash key as generated anew when
+// value_hash_key_id changes.
+func resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHashKeyId(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() != "" {
+		if diff.NewValueKnown("value_hash_key_id") && !diff.HasChange("value_hash_key_id") {
+			return nil
+		}
+
+		for _, k := range []string{"value_hash_key", "value_hash"} {
+			if err := diff.SetNewComputed(k); err != nil {
+				return err
+			}
+		}
+	}
+
+	v, ok := diff.GetOk("value_hash_key_id")
+	if !ok || !diff.NewValueKnown("value_hash_key_id") {
+		return nil
+	}
+
+	secretId := diff.Get("secret_id").(string)
+	keyId := v.(string)
+
+	keySpec, err := kmsKeySpec(keyId, meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: %s", secretId, err)
+	}
+
+	if keySpec != kms.CustomerMasterKeySpecSymmetricDefault {
+		return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: value_hash_key_id %s is an asymmetric %s KMS key, set it to a symmetric KMS key", secretId, keyId, keySpec)
+	}
+
+	return nil
+}
+
+// resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHash decrypts the
+// configured value during plan and compares its hash with the hash of the
+// current secret value last read from Secrets Manager, so a value put outside
+// of Terraform shows as a change of value_hash.
+func resourceAwsSecretsManagerSecretVersionCustomizeDiffValueHash(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() == "" {
+		return nil
+	}
+
+	for _, k := range secretsManagerSecretVersionValueAttributes {
+		if !diff.NewValueKnown(k) {
+			return diff.SetNewComputed("value_hash")
+		}
+	}
+
+	// The hash is computed anew with a new value hash key
+	if !diff.NewValueKnown("value_hash_key") {
+		return nil
+	}
+
+	hashKeyBlob := diff.Get("value_hash_key").(string)
+	if hashKeyBlob == "" {
+		return nil
+	}
+
+	secretId := diff.Get("secret_id").(string)
+
+	hashKey, err := decryptValueHashKey(hashKeyBlob, meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: %s", secretId, err)
+	}
+
+	value, err := decryptCiphertext(diff, diff.Get("encrypted_value").(string), meta)
+	if err != nil {
+		return fmt.Errorf("encryptedssm_secretsmanager_secret_version %q: %s", secretId, err)
+	}
+
+	if o, _ := diff.GetChange("value_hash"); o.(string) != valueHash(hashKey, string(value)) {
+		return diff.SetNew("value_hash", valueHash(hashKey, string(value)))
+	}
+
+	return nil
+}
+
+func resourceAwsSecretsManagerSecretVersionPut(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+	secretId := d.Get("secret_id").(string)
+
+	value, err := decryptCiphertext(d, d.Get("encrypted_value").(string), meta)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Putting Secrets Manager Secret (%s) value", secretId)
+
+	_, err = conn.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(secretId),
+		SecretString: aws.String(string(value)),
+	})
+	if err != nil {
+		return fmt.Errorf("error putting Secrets Manager Secret (%s) value: %s", secretId, err)
+	}
+
+	d.SetId(secretId)
+
+	// Read generates the value hash key under the new value_hash_key_id
+	if d.HasChange("value_hash_key_id") {
+		d.Set("value_hash_key", "")
+	}
+
+	return resourceAwsSecretsManagerSecretVersionRead(d, meta)
+}
+
+func resourceAwsSecretsManagerSecretVersionRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*AWSClient).secretsmanagerconn
+
+	log.Printf("[DEBUG] Reading Secrets Manager Secret (%s) value", d.Id())
+
+	output, err := conn.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(d.Id()),
+		VersionStage: aws.String(secretsManagerVersionStageCurrent),
+	})
+
+	if !d.IsNewResource() && (isAWSErr(err, secretsmanager.ErrCodeResourceNotFoundException, "") ||
+		isAWSErr(err, secretsmanager.ErrCodeInvalidRequestException, "marked for deletion")) {
+		log.Printf("[WARN] Secrets Manager Secret (%s) not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error reading Secrets Manager Secret (%s) value: %w", d.Id(), err)
+	}
+
+	d.Set("secret_id", d.Id())
+	d.Set("arn", output.ARN)
+	d.Set("version_id", output.VersionId)
+
+	// Record a hash of what is in Secrets Manager, plan compares it to the configured value
+	hashKey, err := secretsManagerSecretVersionValueHashKey(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.Set("value_hash", valueHash(hashKey, aws.StringValue(output.SecretString)))
+
+	return nil
+}
+
+// resourceAwsSecretsManagerSecretVersionImport imports the current value of a
+// secret using an ID of the form SECRET-ID,KMS-KEY-ID. As for parameters the
+// value is encrypted under the KMS key and stored as encrypted_value.
+func resourceAwsSecretsManagerSecretVersionImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	conn := meta.(*AWSClient).secretsmanagerconn
+
+	idParts := strings.SplitN(d.Id(), ",", 2)
+	if len(idParts) != 2 || idParts[0] == "" || idParts[1] == "" {
+		return nil, fmt.Errorf("unexpected format of ID (%q), expected SECRET-ID,KMS-KEY-ID", d.Id())
+	}
+
+	secretId := idParts[0]
+	keyId := idParts[1]
+
+	// The value is encrypted without encryption_algorithm, which asymmetric
+	// keys require
+	keySpec, err := kmsKeySpec(keyId, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if keySpec != kms.CustomerMasterKeySpecSymmetricDefault {
+		return nil, fmt.Errorf("KMS key %s is an asymmetric %s key, Secrets Manager Secret values can only be imported with a symmetric KMS key", keyId, keySpec)
+	}
+
+	output, err := conn.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId:     aws.String(secretId),
+		VersionStage: aws.String(secretsManagerVersionStageCurrent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading Secrets Manager Secret (%s) value: %w", secretId, err)
+	}
+
+	encryptedValue, err := encryptValue(&kms.EncryptInput{
+		KeyId:     aws.String(keyId),
+		Plaintext: []byte(aws.StringValue(output.SecretString)),
+	}, meta)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting Secrets Manager Secret (%s) value: %s", secretId, err)
+	}
+
+	log.Printf("[INFO] Secrets Manager Secret (%s) value imported with encrypted_value: %s", secretId, encryptedValue)
+
+	d.SetId(secretId)
+	d.Set("secret_id", secretId)
+	d.Set("encryption_key", keyId)
+	d.Set("encryption_scheme", encryptionSchemeKms)
+	d.Set("encrypted_value", encryptedValue)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// resourceAwsSecretsManagerSecretVersionDelete only removes the value from
+// state. Secrets Manager always keeps a current version, so the value is
+// deleted along with the secret.
+func resourceAwsSecretsManagerSecretVersionDelete(d *schema.ResourceData, meta interface{}) error {
+	log.Printf("[INFO] Removing Secrets Manager Secret (%s) value from state, it is deleted with the secret", d.Id())
+
+	return nil
+}
+
+// secretsManagerSecretVersionValueHashKey returns the HMAC key used to hash
+// the secret value, generating one the first time the value is read under
+// value_hash_key_id or else the key the secret is encrypted with. The secret
+// key is used rather than encryption_key, which may be asymmetric or name a
+// Vault Transit key. As the secret key may change outside of this resource the
+// value hash key is generated anew once it is no longer under it.
+func secretsManagerSecretVersionValueHashKey(d *schema.ResourceData, meta interface{}) ([]byte, error) {
+	hashKeyBlob := d.Get("value_hash_key").(string)
+	keyId := d.Get("value_hash_key_id").(string)
+
+	var err error
+	if keyId == "" {
+		if keyId, err = secretsManagerSecretKmsKeyId(d.Id(), meta); err != nil {
+			return nil, err
+		}
+	}
+
+	if hashKeyBlob != "" {
+		hashKey, hashKeyArn, err := decryptValueHashKeyWithArn(hashKeyBlob, meta)
+		if err != nil {
+			return nil, err
+		}
+
+		// value_hash_key_id changes are handled by plan and Put
+		if _, ok := d.GetOk("value_hash_key_id"); ok {
+			return hashKey, nil
+		}
+
+		keyArn, err := resolveKmsKeyArn(keyId, meta)
+		if err != nil {
+			return nil, err
+		}
+
+		if hashKeyArn == keyArn {
+			return hashKey, nil
+		}
+
+		log.Printf("[INFO] Secrets Manager Secret (%s) is encrypted with KMS key %s, generating a new value hash key", d.Id(), keyArn)
+	}
+
+	if hashKeyBlob, err = generateValueHashKey(keyId, meta); err != nil {
+		// Key policies, such as that of the AWS managed key, may only allow
+		// the key of the secret to be used through Secrets Manager
+		if _, ok := d.GetOk("value_hash_key_id"); !ok {
+			err = fmt.Errorf("%s, set value_hash_key_id to a symmetric KMS key data keys can be generated with", err)
+		}
+
+		return nil, err
+	}
+
+	d.Set("value_hash_key", hashKeyBlob)
+
+	return decryptValueHashKey(hashKeyBlob, meta)
+}
+
+// secretsManagerSecretKmsKeyId returns the KMS key a secret is encrypted with,
+// the AWS managed key of Secrets Manager when it has no kms_key_id.
+func secretsManagerSecretKmsKeyId(secretId string, meta interface{}) (string, error) {
+	conn := meta.(*AWSClient).secretsmanagerconn
+
+	output, err := conn.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretId),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing Secrets Manager Secret (%s): %w", secretId, err)
+	}
+
+	if keyId := aws.StringValue(output.KmsKeyId); keyId != "" {
+		return keyId, nil
+	}
+
+	return secretsManagerDefaultKmsKeyId, nil
+}
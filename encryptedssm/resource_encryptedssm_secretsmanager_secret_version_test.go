@@ -0,0 +1,467 @@
+package encryptedssm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestResourceAwsSecretsManagerSecretVersion_drift(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	conn := client.secretsmanagerconn.(*fakeSecretsManager)
+	resourceName := "encryptedssm_secretsmanager_secret_version.test"
+	defaultKeyArn := kmsconn.addKey("5678abcd-56ab-78cd-90ef-5678901234ab", kms.CustomerMasterKeySpecSymmetricDefault, secretsManagerDefaultKmsKeyId)
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", map[string]string{"environment": "prod"}, "MyStr0ngp@ss!")
+	config := testResourceAwsSecretsManagerSecretVersionConfig(encryptedValue)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSecretsManagerSecretDestroy(conn),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSecretsManagerSecret(conn, func(s *fakeSecretsManagerSecret) error {
+						if s.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(s.Value))
+						}
+						return nil
+					}),
+					resource.TestCheckResourceAttr(resourceName, "encrypted_value", encryptedValue),
+					resource.TestCheckResourceAttrSet(resourceName, "arn"),
+					resource.TestCheckResourceAttrSet(resourceName, "version_id"),
+					resource.TestCheckResourceAttrSet(resourceName, "value_hash"),
+					// The secret has no kms_key_id, so is encrypted with the AWS managed key
+					testCheckValueHashKeyArn(kmsconn, resourceName, defaultKeyArn),
+				),
+			},
+			{
+				// A new ciphertext of the same value is not a change
+				Config:   testResourceAwsSecretsManagerSecretVersionConfig(kmsconn.testEncrypt(t, "alias/test", map[string]string{"environment": "prod"}, "MyStr0ngp@ss!")),
+				PlanOnly: true,
+			},
+			{
+				PreConfig: func() {
+					conn.update(testSecretsManagerSecretName, func(s *fakeSecretsManagerSecret) {
+						s.Value = "changed outside terraform"
+						s.Versions++
+						s.VersionId = fmt.Sprintf("00000000-0000-0000-0000-%012d", s.Versions)
+					})
+				},
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSecretsManagerSecret(conn, func(s *fakeSecretsManagerSecret) error {
+						if s.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected drifted value to be restored")
+						}
+						if s.Versions != 3 {
+							return fmt.Errorf("expected 3 versions, got %d", s.Versions)
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSecretsManagerSecretVersion_import(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	conn := client.secretsmanagerconn.(*fakeSecretsManager)
+	resourceName := "encryptedssm_secretsmanager_secret_version.test"
+	kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+	kmsconn.addKey("5678abcd-56ab-78cd-90ef-5678901234ab", kms.CustomerMasterKeySpecSymmetricDefault, secretsManagerDefaultKmsKeyId)
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSecretsManagerSecretDestroy(conn),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceAwsSecretsManagerSecretVersionConfigNoContext(kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")),
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateIdFunc: testResourceAwsSecretsManagerSecretVersionImportStateIdFunc(resourceName, "alias/test"),
+				ImportStateVerify: true,
+				// The value is encrypted again and a new hash key is generated on import
+				ImportStateVerifyIgnore: []string{"encrypted_value", "value_hash", "value_hash_key"},
+			},
+			{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateIdFunc: testResourceAwsSecretsManagerSecretVersionImportStateIdFunc(resourceName, "alias/test-rsa"),
+				ExpectError:       regexp.MustCompile(`KMS key alias/test-rsa is an asymmetric RSA_2048 key`),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSecretsManagerSecretVersion_valueHashKeyId(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	conn := client.secretsmanagerconn.(*fakeSecretsManager)
+	resourceName := "encryptedssm_secretsmanager_secret_version.test"
+	testKeyArn, _ := kmsconn.keyArn(aws.String("alias/test"))
+	otherKeyArn := kmsconn.addKey("abcd1234-ab12-cd34-ef56-abcdef123456", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	rsaKeyArn := kmsconn.addKey("0987dcba-09fe-87dc-65ba-ab0987654321", kms.CustomerMasterKeySpecRsa2048, "alias/test-rsa")
+
+	encryptedValue := base64.StdEncoding.EncodeToString(kmsconn.encrypt(rsaKeyArn, kms.EncryptionAlgorithmSpecRsaesOaepSha256, nil, []byte("MyStr0ngp@ss!")))
+
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testAccProviderFactories(client),
+		CheckDestroy:      testCheckFakeSecretsManagerSecretDestroy(conn),
+		Steps: []resource.TestStep{
+			{
+				Config:      testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/test", `"alias/test-rsa"`),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`value_hash_key_id alias/test-rsa is an asymmetric RSA_2048 KMS key`),
+			},
+			{
+				// Without value_hash_key_id the hash key is generated under
+				// the kms_key_id of the secret, not the asymmetric encryption_key
+				Config: testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/test", "null"),
+				Check: resource.ComposeTestCheckFunc(
+					testCheckFakeSecretsManagerSecret(conn, func(s *fakeSecretsManagerSecret) error {
+						if s.Value != "MyStr0ngp@ss!" {
+							return fmt.Errorf("expected decrypted value to be stored, got %d bytes", len(s.Value))
+						}
+						return nil
+					}),
+					testCheckValueHashKeyArn(kmsconn, resourceName, testKeyArn),
+				),
+			},
+			{
+				// Changing value_hash_key_id updates the version in place
+				Config: testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/test", `"alias/other"`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(resourceName, "value_hash_key_id", "alias/other"),
+					testCheckValueHashKeyArn(kmsconn, resourceName, otherKeyArn),
+				),
+			},
+			{
+				Config: testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/test", "null"),
+				Check:  testCheckValueHashKeyArn(kmsconn, resourceName, testKeyArn),
+			},
+			{
+				// The hash key follows a new kms_key_id of the secret
+				Config: testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/other", "null"),
+			},
+			{
+				Config: testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, "alias/other", "null"),
+				Check:  testCheckValueHashKeyArn(kmsconn, resourceName, otherKeyArn),
+			},
+		},
+	})
+}
+
+func TestResourceAwsSecretsManagerSecretVersion_equivalentCiphertextDiff(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	encryptionContext := map[string]string{"environment": "prod"}
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", encryptionContext, "MyStr0ngp@ss!")
+
+	hashKeyBlob, err := generateValueHashKey("alias/test", client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	hashKey, err := decryptValueHashKey(hashKeyBlob, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	state := &terraform.InstanceState{
+		ID: testSecretsManagerSecretName,
+		Attributes: map[string]string{
+			"id":                             testSecretsManagerSecretName,
+			"secret_id":                      testSecretsManagerSecretName,
+			"encryption_key":                 "alias/test",
+			"encryption_scheme":              encryptionSchemeKms,
+			"encryption_context.%":           "1",
+			"encryption_context.environment": "prod",
+			"encrypted_value":                encryptedValue,
+			"value_hash":                     valueHash(hashKey, "MyStr0ngp@ss!"),
+			"value_hash_key":                 hashKeyBlob,
+		},
+	}
+
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"secret_id":          testSecretsManagerSecretName,
+		"encryption_key":     "alias/test",
+		"encrypted_value":    kmsconn.testEncrypt(t, "alias/test", encryptionContext, "MyStr0ngp@ss!"),
+		"encryption_context": map[string]interface{}{"environment": "prod"},
+	})
+
+	r := resourceAwsSecretsManagerSecretVersion(func() interface{} { return client })
+	diff, err := r.Diff(context.Background(), state, config, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if diff != nil && !diff.Empty() {
+		t.Fatalf("expected a new ciphertext of the same value to plan clean, got %#v", diff.Attributes)
+	}
+}
+
+func TestResourceAwsSecretsManagerSecretVersion_valueHashKeyIdDiff(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	kmsconn.addKey("abcd1234-ab12-cd34-ef56-abcdef123456", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+	encryptedValue := kmsconn.testEncrypt(t, "alias/test", nil, "MyStr0ngp@ss!")
+
+	hashKeyBlob, err := generateValueHashKey("alias/test", client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	hashKey, err := decryptValueHashKey(hashKeyBlob, client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		StateKeyId       string
+		ConfigKeyId      string
+		ExpectNewHashKey bool
+	}{
+		{},
+		{
+			StateKeyId:  "alias/test",
+			ConfigKeyId: "alias/test",
+		},
+		{
+			ConfigKeyId:      "alias/other",
+			ExpectNewHashKey: true,
+		},
+		{
+			StateKeyId:       "alias/test",
+			ConfigKeyId:      "alias/other",
+			ExpectNewHashKey: true,
+		},
+		{
+			StateKeyId:       "alias/other",
+			ExpectNewHashKey: true,
+		},
+	}
+
+	for _, tc := range cases {
+		state := &terraform.InstanceState{
+			ID: testSecretsManagerSecretName,
+			Attributes: map[string]string{
+				"id":                testSecretsManagerSecretName,
+				"secret_id":         testSecretsManagerSecretName,
+				"encryption_key":    "alias/test",
+				"encryption_scheme": encryptionSchemeKms,
+				"encrypted_value":   encryptedValue,
+				"value_hash":        valueHash(hashKey, "MyStr0ngp@ss!"),
+				"value_hash_key":    hashKeyBlob,
+				"value_hash_key_id": tc.StateKeyId,
+			},
+		}
+
+		config := map[string]interface{}{
+			"secret_id":       testSecretsManagerSecretName,
+			"encryption_key":  "alias/test",
+			"encrypted_value": encryptedValue,
+		}
+		if tc.ConfigKeyId != "" {
+			config["value_hash_key_id"] = tc.ConfigKeyId
+		}
+
+		r := resourceAwsSecretsManagerSecretVersion(func() interface{} { return client })
+		diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), client)
+		if err != nil {
+			t.Fatalf("err with %q to %q: %s", tc.StateKeyId, tc.ConfigKeyId, err)
+		}
+
+		var newHashKey bool
+		if diff != nil && diff.Attributes["value_hash_key"] != nil {
+			newHashKey = diff.Attributes["value_hash_key"].NewComputed
+		}
+
+		if newHashKey != tc.ExpectNewHashKey {
+			t.Fatalf("expected a new value hash key with value_hash_key_id %q to %q to be %t", tc.StateKeyId, tc.ConfigKeyId, tc.ExpectNewHashKey)
+		}
+
+		if diff != nil && diff.RequiresNew() {
+			t.Fatalf("expected value_hash_key_id %q to %q to update in place", tc.StateKeyId, tc.ConfigKeyId)
+		}
+	}
+}
+
+func TestResourceAwsSecretsManagerSecretVersion_secretKmsKeyIdRead(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+	conn := client.secretsmanagerconn.(*fakeSecretsManager)
+	testKeyArn, _ := kmsconn.keyArn(aws.String("alias/test"))
+	otherKeyArn := kmsconn.addKey("abcd1234-ab12-cd34-ef56-abcdef123456", kms.CustomerMasterKeySpecSymmetricDefault, "alias/other")
+
+	if _, err := conn.CreateSecret(&secretsmanager.CreateSecretInput{
+		Name:     aws.String(testSecretsManagerSecretName),
+		KmsKeyId: aws.String("alias/test"),
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := conn.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(testSecretsManagerSecretName),
+		SecretString: aws.String("MyStr0ngp@ss!"),
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r := resourceAwsSecretsManagerSecretVersion(func() interface{} { return client })
+	d := r.Data(&terraform.InstanceState{ID: testSecretsManagerSecretName})
+
+	read := func(keyArn string) string {
+		t.Helper()
+
+		if err := r.Read(d, client); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		hashKeyBlob := d.Get("value_hash_key").(string)
+		_, hashKeyArn, err := decryptValueHashKeyWithArn(hashKeyBlob, client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if hashKeyArn != keyArn {
+			t.Fatalf("expected value_hash_key to be generated under %s, got %s", keyArn, hashKeyArn)
+		}
+
+		return hashKeyBlob
+	}
+
+	hashKeyBlob := read(testKeyArn)
+
+	if v := read(testKeyArn); v != hashKeyBlob {
+		t.Fatalf("expected the value hash key to be kept while the secret key is unchanged")
+	}
+
+	conn.update(testSecretsManagerSecretName, func(s *fakeSecretsManagerSecret) {
+		s.KmsKeyId = "alias/other"
+	})
+
+	read(otherKeyArn)
+
+	// A value_hash_key_id is kept regardless of the secret key
+	d.Set("value_hash_key_id", "alias/test")
+	d.Set("value_hash_key", hashKeyBlob)
+	read(testKeyArn)
+}
+
+// testCheckValueHashKeyArn checks the value_hash_key of a resource was
+// generated under the given KMS key.
+func testCheckValueHashKeyArn(kmsconn *fakeKMS, name, keyArn string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("not found: %s", name)
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(rs.Primary.Attributes["value_hash_key"])
+		if err != nil {
+			return fmt.Errorf("%s: error decoding value_hash_key: %s", name, err)
+		}
+
+		output, err := kmsconn.Decrypt(&kms.DecryptInput{CiphertextBlob: blob})
+		if err != nil {
+			return fmt.Errorf("%s: error decrypting value_hash_key: %s", name, err)
+		}
+
+		if aws.StringValue(output.KeyId) != keyArn {
+			return fmt.Errorf("%s: expected value_hash_key to be generated under %s, got %s", name, keyArn, aws.StringValue(output.KeyId))
+		}
+
+		return nil
+	}
+}
+
+// testResourceAwsSecretsManagerSecretVersionImportStateIdFunc returns the
+// SECRET-ID,KMS-KEY-ID import ID of the secret version in state.
+func testResourceAwsSecretsManagerSecretVersionImportStateIdFunc(resourceName, keyId string) resource.ImportStateIdFunc {
+	return func(s *terraform.State) (string, error) {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return "", fmt.Errorf("not found: %s", resourceName)
+		}
+
+		return rs.Primary.ID + "," + keyId, nil
+	}
+}
+
+func testResourceAwsSecretsManagerSecretVersionConfig(encryptedValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_secretsmanager_secret" "test" {
+  name                    = %[2]q
+  recovery_window_in_days = 0
+}
+
+resource "encryptedssm_secretsmanager_secret_version" "test" {
+  secret_id       = encryptedssm_secretsmanager_secret.test.id
+  encryption_key  = "alias/test"
+  encrypted_value = %[3]q
+
+  encryption_context = {
+    environment = "prod"
+  }
+}
+`, testRegion, testSecretsManagerSecretName, encryptedValue)
+}
+
+func testResourceAwsSecretsManagerSecretVersionConfigNoContext(encryptedValue string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_secretsmanager_secret" "test" {
+  name                    = %[2]q
+  recovery_window_in_days = 0
+}
+
+resource "encryptedssm_secretsmanager_secret_version" "test" {
+  secret_id       = encryptedssm_secretsmanager_secret.test.id
+  encryption_key  = "alias/test"
+  encrypted_value = %[3]q
+}
+`, testRegion, testSecretsManagerSecretName, encryptedValue)
+}
+
+func testResourceAwsSecretsManagerSecretVersionConfigValueHashKeyId(encryptedValue, kmsKeyId, valueHashKeyId string) string {
+	return fmt.Sprintf(`
+provider "encryptedssm" {
+  region = %[1]q
+}
+
+resource "encryptedssm_secretsmanager_secret" "test" {
+  name                    = %[2]q
+  kms_key_id              = %[5]q
+  recovery_window_in_days = 0
+}
+
+resource "encryptedssm_secretsmanager_secret_version" "test" {
+  secret_id            = encryptedssm_secretsmanager_secret.test.id
+  encryption_key       = "alias/test-rsa"
+  encryption_algorithm = "RSAES_OAEP_SHA_256"
+  encrypted_value      = %[3]q
+  value_hash_key_id    = %[4]s
+}
+`, testRegion, testSecretsManagerSecretName, encryptedValue, valueHashKeyId, kmsKeyId)
+}
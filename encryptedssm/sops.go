@@ -0,0 +1,357 @@
+package encryptedssm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"gopkg.in/yaml.v2"
+)
+
+// SOPS documents are YAML or JSON, which the YAML parser also reads, with
+// each encrypted leaf replaced by an AES-256-GCM ciphertext under a data key.
+// The data key is stored encrypted by each KMS key in the sops metadata key.
+// Each leaf is authenticated with its path in the document as additional
+// data, and the document as a whole by a SHA-512 MAC over its leaves in
+// order, itself encrypted with the data key.
+
+// sopsEncryptedValueRegexp matches a leaf encrypted by SOPS.
+var sopsEncryptedValueRegexp = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+type sopsDocument struct {
+	Sops *sopsMetadata `yaml:"sops"`
+}
+
+type sopsMetadata struct {
+	KMS               []sopsKmsKey `yaml:"kms"`
+	LastModified      string       `yaml:"lastmodified"`
+	MAC               string       `yaml:"mac"`
+	MACOnlyEncrypted  bool         `yaml:"mac_only_encrypted"`
+	UnencryptedSuffix string       `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string       `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string       `yaml:"unencrypted_regex"`
+	EncryptedRegex    string       `yaml:"encrypted_regex"`
+}
+
+type sopsKmsKey struct {
+	Arn     string            `yaml:"arn"`
+	Context map[string]string `yaml:"context"`
+	Enc     string            `yaml:"enc"`
+}
+
+// decryptSopsDocument returns the leaves of a SOPS document by their path,
+// the keys and list indexes leading to them joined with a slash. Leaves that
+// are not encrypted are returned as they are.
+func decryptSopsDocument(data []byte, meta interface{}) (map[string]string, error) {
+	var doc sopsDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing SOPS document: %s", err)
+	}
+
+	if doc.Sops == nil {
+		return nil, fmt.Errorf("error parsing SOPS document: no sops metadata found")
+	}
+
+	dataKey, err := sopsDataKey(doc.Sops, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptSopsTree(data, doc.Sops, dataKey)
+}
+
+// decryptSopsTree decrypts the leaves of a SOPS document with its data key
+// and verifies the document MAC.
+func decryptSopsTree(data []byte, metadata *sopsMetadata, dataKey []byte) (map[string]string, error) {
+	// The MAC depends on the order of the leaves, which a map loses
+	var tree yaml.MapSlice
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("error parsing SOPS document: %s", err)
+	}
+
+	t := &sopsTree{
+		metadata: metadata,
+		dataKey:  dataKey,
+		hash:     sha512.New(),
+		leaves:   make(map[string]string),
+	}
+
+	for _, item := range tree {
+		k := fmt.Sprint(item.Key)
+		if k == "sops" {
+			continue
+		}
+
+		if err := t.decrypt(item.Value, []string{k}, []string{k}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := t.verifyMac(); err != nil {
+		return nil, err
+	}
+
+	return t.leaves, nil
+}
+
+// sopsDataKey decrypts the document data key with the first KMS key able to.
+func sopsDataKey(metadata *sopsMetadata, meta interface{}) ([]byte, error) {
+	if len(metadata.KMS) == 0 {
+		return nil, fmt.Errorf("error decrypting SOPS data key: the document is not encrypted with a KMS key")
+	}
+
+	var errs []string
+	for _, key := range metadata.KMS {
+		blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key.Enc))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", key.Arn, err))
+			continue
+		}
+
+		input := &kms.DecryptInput{
+			CiphertextBlob: blob,
+			KeyId:          aws.String(key.Arn),
+		}
+
+		if len(key.Context) > 0 {
+			input.EncryptionContext = aws.StringMap(key.Context)
+		}
+
+		result, err := kmsDecrypt(input, meta)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", key.Arn, err))
+			continue
+		}
+
+		return result.Plaintext, nil
+	}
+
+	return nil, fmt.Errorf("error decrypting SOPS data key: %s", strings.Join(errs, "; "))
+}
+
+// sopsTree decrypts the leaves of a document and hashes them into its MAC.
+type sopsTree struct {
+	metadata *sopsMetadata
+	dataKey  []byte
+	hash     hash.Hash
+	leaves   map[string]string
+}
+
+// decrypt decrypts the leaves under value. SOPS does not include list
+// indexes in the additional data, so the path they are authenticated with
+// differs from the path they are returned by.
+func (t *sopsTree) decrypt(value interface{}, path, aadPath []string) error {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if err := t.decrypt(item.Value, append(path[:len(path):len(path)], key), append(aadPath[:len(aadPath):len(aadPath)], key)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if err := t.decrypt(child, append(path[:len(path):len(path)], strconv.Itoa(i)), aadPath); err != nil {
+				return err
+			}
+		}
+	case nil:
+		// SOPS neither encrypts nor hashes null values
+		t.leaves[strings.Join(path, "/")] = ""
+	default:
+		encrypted := t.metadata.encrypted(aadPath)
+
+		var plaintext string
+		var macValue []byte
+		var err error
+
+		if s, ok := v.(string); ok && encrypted {
+			plaintext, macValue, err = sopsDecryptValue(t.dataKey, s, strings.Join(aadPath, ":")+":")
+		} else if encrypted {
+			err = fmt.Errorf("value is not encrypted")
+		} else {
+			plaintext = fmt.Sprint(v)
+			macValue, err = sopsMacValue(v)
+		}
+
+		if err != nil {
+			return fmt.Errorf("error decrypting SOPS value %s: %s", strings.Join(path, "/"), err)
+		}
+
+		if !t.metadata.MACOnlyEncrypted || encrypted {
+			t.hash.Write(macValue)
+		}
+
+		t.leaves[strings.Join(path, "/")] = plaintext
+	}
+
+	return nil
+}
+
+// verifyMac compares the MAC of the decrypted leaves with the document MAC,
+// which is encrypted with the last modification time as additional data.
+func (t *sopsTree) verifyMac() error {
+	if t.metadata.MAC == "" {
+		return fmt.Errorf("error verifying SOPS document: no MAC found")
+	}
+
+	lastModified, err := time.Parse(time.RFC3339, t.metadata.LastModified)
+	if err != nil {
+		return fmt.Errorf("error verifying SOPS document: lastmodified: %s", err)
+	}
+
+	mac, _, err := sopsDecryptValue(t.dataKey, t.metadata.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("error verifying SOPS document: error decrypting MAC: %s", err)
+	}
+
+	if !hmac.Equal([]byte(mac), []byte(fmt.Sprintf("%X", t.hash.Sum(nil)))) {
+		return fmt.Errorf("error verifying SOPS document: MAC mismatch, the document was modified after it was encrypted")
+	}
+
+	return nil
+}
+
+// encrypted returns whether SOPS encrypts the leaf at path given the
+// unencrypted and encrypted suffix and regex options of the document, applied
+// in the same order as SOPS.
+func (m *sopsMetadata) encrypted(path []string) bool {
+	encrypted := true
+
+	if m.UnencryptedSuffix != "" {
+		for _, k := range path {
+			if strings.HasSuffix(k, m.UnencryptedSuffix) {
+				encrypted = false
+				break
+			}
+		}
+	}
+
+	if m.EncryptedSuffix != "" {
+		encrypted = false
+		for _, k := range path {
+			if strings.HasSuffix(k, m.EncryptedSuffix) {
+				encrypted = true
+				break
+			}
+		}
+	}
+
+	if m.UnencryptedRegex != "" {
+		for _, k := range path {
+			if matched, _ := regexp.MatchString(m.UnencryptedRegex, k); matched {
+				encrypted = false
+				break
+			}
+		}
+	}
+
+	if m.EncryptedRegex != "" {
+		encrypted = false
+		for _, k := range path {
+			if matched, _ := regexp.MatchString(m.EncryptedRegex, k); matched {
+				encrypted = true
+				break
+			}
+		}
+	}
+
+	return encrypted
+}
+
+// sopsDecryptValue decrypts a SOPS encrypted leaf, returning its plaintext and
+// the form SOPS hashes it in. Numbers and booleans are returned in their
+// string form. Empty values are not encrypted by SOPS.
+func sopsDecryptValue(dataKey []byte, value, additionalData string) (string, []byte, error) {
+	if value == "" {
+		return "", nil, nil
+	}
+
+	match := sopsEncryptedValueRegexp.FindStringSubmatch(value)
+	if match == nil {
+		return "", nil, fmt.Errorf("value is not encrypted")
+	}
+
+	var parts [3][]byte
+	for i, name := range []string{"data", "iv", "tag"} {
+		b, err := base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return "", nil, fmt.Errorf("%s is not valid base64: %s", name, err)
+		}
+		parts[i] = b
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(parts[1]))
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(additionalData))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var macValue interface{}
+
+	switch valueType := match[4]; valueType {
+	case "str", "bytes":
+		macValue = string(plaintext)
+	case "int":
+		macValue, err = strconv.Atoi(string(plaintext))
+	case "float":
+		macValue, err = strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		macValue, err = strconv.ParseBool(string(plaintext))
+	default:
+		return "", nil, fmt.Errorf("unknown type %q", valueType)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	b, err := sopsMacValue(macValue)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return string(plaintext), b, nil
+}
+
+// sopsMacValue returns a leaf value as SOPS writes it to the MAC.
+func sopsMacValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case int64:
+		return []byte(strconv.FormatInt(v, 10)), nil
+	case uint64:
+		return []byte(strconv.FormatUint(v, 10)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	}
+
+	return nil, fmt.Errorf("unsupported value type %T", value)
+}
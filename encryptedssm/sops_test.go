@@ -0,0 +1,304 @@
+package encryptedssm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDecryptSopsDocument(t *testing.T) {
+	client, _, kmsconn := newTestAWSClient()
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	keyArn, _ := kmsconn.keyArn(aws.String("alias/test"))
+	encryptedDataKey, err := kmsconn.Encrypt(&kms.EncryptInput{
+		KeyId:             aws.String(keyArn),
+		EncryptionContext: aws.StringMap(map[string]string{"app": "test"}),
+		Plaintext:         dataKey,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	yamlDocument := fmt.Sprintf(`db:
+  user_unencrypted: app
+  password: %s
+hosts:
+- %s
+- %s
+sops:
+  kms:
+  - arn: %s
+    context:
+      app: test
+    enc: %s
+  lastmodified: "2024-03-01T12:00:00Z"
+  mac: %s
+  unencrypted_suffix: _unencrypted
+  version: 3.7.1
+`,
+		testSopsEncrypt(t, dataKey, "MyStr0ngp@ss!", "db:password:"),
+		testSopsEncrypt(t, dataKey, "a.example.com", "hosts:"),
+		testSopsEncrypt(t, dataKey, "b.example.com", "hosts:"),
+		keyArn,
+		base64.StdEncoding.EncodeToString(encryptedDataKey.CiphertextBlob),
+		testSopsMac(t, dataKey, "2024-03-01T12:00:00Z", "app", "MyStr0ngp@ss!", "a.example.com", "b.example.com"),
+	)
+
+	jsonDocument := fmt.Sprintf(`{
+  "api_key": %q,
+  "sops": {
+    "kms": [{"arn": %q, "context": {"app": "test"}, "enc": %q}],
+    "lastmodified": "2024-03-01T12:00:00Z",
+    "mac": %q
+  }
+}`,
+		testSopsEncrypt(t, dataKey, "0123456789", "api_key:"),
+		keyArn,
+		base64.StdEncoding.EncodeToString(encryptedDataKey.CiphertextBlob),
+		testSopsMac(t, dataKey, "2024-03-01T12:00:00Z", "0123456789"),
+	)
+
+	testCases := []struct {
+		document string
+		expected map[string]string
+	}{
+		{
+			document: yamlDocument,
+			expected: map[string]string{
+				"db/user_unencrypted": "app",
+				"db/password":         "MyStr0ngp@ss!",
+				"hosts/0":             "a.example.com",
+				"hosts/1":             "b.example.com",
+			},
+		},
+		{
+			document: jsonDocument,
+			expected: map[string]string{
+				"api_key": "0123456789",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		leaves, err := decryptSopsDocument([]byte(tc.document), client)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		if len(leaves) != len(tc.expected) {
+			t.Fatalf("expected %d leaves, got %d", len(tc.expected), len(leaves))
+		}
+
+		for k, v := range tc.expected {
+			if leaves[k] != v {
+				t.Errorf("expected %s to be %q, got %q", k, v, leaves[k])
+			}
+		}
+	}
+
+	// A value moved to another path fails authentication
+	moved := strings.Replace(yamlDocument, "  password:", "  secret:", 1)
+	if _, err := decryptSopsDocument([]byte(moved), client); err == nil {
+		t.Fatalf("expected error decrypting a value moved to another path")
+	}
+
+	if _, err := decryptSopsDocument([]byte("db:\n  user: app\n"), client); err == nil {
+		t.Fatalf("expected error decrypting a document without sops metadata")
+	}
+
+	// Changes that keep each value authenticated with its path fail the MAC
+	lines := strings.Split(yamlDocument, "\n")
+	for name, document := range map[string]string{
+		"unencrypted value changed": strings.Replace(yamlDocument, "user_unencrypted: app", "user_unencrypted: admin", 1),
+		"list elements swapped":     strings.Join(append(append(append([]string{}, lines[:4]...), lines[5], lines[4]), lines[6:]...), "\n"),
+		"list element removed":      strings.Join(append(append([]string{}, lines[:5]...), lines[6:]...), "\n"),
+		"lastmodified changed":      strings.Replace(yamlDocument, "2024-03-01T12:00:00Z", "2024-03-02T12:00:00Z", 1),
+	} {
+		if _, err := decryptSopsDocument([]byte(document), client); err == nil || !strings.Contains(err.Error(), "MAC") {
+			t.Errorf("%s: expected MAC verification to fail, got %v", name, err)
+		}
+	}
+
+	noMac := strings.Replace(jsonDocument, `"mac"`, `"unused"`, 1)
+	if _, err := decryptSopsDocument([]byte(noMac), client); err == nil || !strings.Contains(err.Error(), "no MAC found") {
+		t.Fatalf("expected error decrypting a document without a MAC, got %v", err)
+	}
+
+	// Values at encrypted paths must be encrypted
+	plain := strings.Replace(yamlDocument, "user_unencrypted: app", "user: app", 1)
+	if _, err := decryptSopsDocument([]byte(plain), client); err == nil || !strings.Contains(err.Error(), "db/user: value is not encrypted") {
+		t.Fatalf("expected error decrypting an unencrypted value, got %v", err)
+	}
+}
+
+// The documents in testdata/sops were written by the sops binary and are
+// copied from the SOPS v3.8.1 source. Their data keys are encrypted with the
+// public SOPS functional test PGP key, which decrypted the keys below.
+func TestDecryptSopsTree_sopsDocuments(t *testing.T) {
+	testCases := []struct {
+		file     string
+		dataKey  string
+		expected map[string]string
+	}{
+		{
+			file:    "example.json",
+			dataKey: "faf6a891866fac550ef548b4e5f6fbc98fccc6827cd943cc8d7539747f1d87bd",
+			expected: map[string]string{
+				"firstName":             "John",
+				"lastName":              "Smith",
+				"age":                   "25.4",
+				"address/city":          "New York",
+				"address/postalCode":    "10021-3100",
+				"address/state":         "NY",
+				"address/streetAddress": "21 2nd Street",
+				"phoneNumbers/0/number": "212 555-1234",
+				"phoneNumbers/0/type":   "home",
+				"phoneNumbers/1/number": "646 555-4567",
+				"phoneNumbers/1/type":   "office",
+				"anEmptyValue":          "",
+			},
+		},
+		{
+			// Encrypted comments are not part of the MAC
+			file:    "comments.enc.yaml",
+			dataKey: "61bec946f5783fd4cea3d1b1ce407fce2b6e81fc301cc539c422015e27f6d1be",
+			expected: map[string]string{
+				"lorem": "ipsum",
+				"dolor": "sit",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "sops", tc.file))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		dataKey, err := hex.DecodeString(tc.dataKey)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		var doc sopsDocument
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		leaves, err := decryptSopsTree(data, doc.Sops, dataKey)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.file, err)
+		}
+
+		if !reflect.DeepEqual(leaves, tc.expected) {
+			t.Errorf("%s: expected leaves %v, got %v", tc.file, tc.expected, leaves)
+		}
+	}
+
+	// Swapping the phone numbers keeps each authenticated with its path,
+	// phoneNumbers:number:, but changes the MAC
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "sops", "example.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	var numbers []int
+	for i, line := range lines {
+		if strings.Contains(line, `"number":`) {
+			numbers = append(numbers, i)
+		}
+	}
+	lines[numbers[0]], lines[numbers[1]] = lines[numbers[1]], lines[numbers[0]]
+
+	var doc sopsDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dataKey, _ := hex.DecodeString(testCases[0].dataKey)
+	if _, err := decryptSopsTree([]byte(strings.Join(lines, "\n")), doc.Sops, dataKey); err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+		t.Fatalf("expected MAC verification to fail, got %v", err)
+	}
+}
+
+func TestSopsMetadataEncrypted(t *testing.T) {
+	testCases := []struct {
+		metadata sopsMetadata
+		path     []string
+		expected bool
+	}{
+		{sopsMetadata{}, []string{"db", "password"}, true},
+		{sopsMetadata{UnencryptedSuffix: "_unencrypted"}, []string{"db_unencrypted", "password"}, false},
+		{sopsMetadata{UnencryptedSuffix: "_unencrypted"}, []string{"db", "password"}, true},
+		{sopsMetadata{EncryptedSuffix: "_encrypted"}, []string{"db", "password_encrypted"}, true},
+		{sopsMetadata{EncryptedSuffix: "_encrypted"}, []string{"db", "password"}, false},
+		{sopsMetadata{UnencryptedRegex: "^user$"}, []string{"db", "user"}, false},
+		{sopsMetadata{EncryptedRegex: "^(password|token)$"}, []string{"db", "password"}, true},
+		{sopsMetadata{EncryptedRegex: "^(password|token)$"}, []string{"db", "user"}, false},
+	}
+
+	for _, tc := range testCases {
+		if encrypted := tc.metadata.encrypted(tc.path); encrypted != tc.expected {
+			t.Errorf("%+v %v: expected %t, got %t", tc.metadata, tc.path, tc.expected, encrypted)
+		}
+	}
+}
+
+// testSopsEncrypt encrypts a string leaf as SOPS does.
+func testSopsEncrypt(t *testing.T, dataKey []byte, plaintext, additionalData string) string {
+	t.Helper()
+
+	iv := make([]byte, 32)
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag))
+}
+
+// testSopsMac returns the document MAC of the given leaves as SOPS encrypts it.
+func testSopsMac(t *testing.T, dataKey []byte, lastModified string, leaves ...string) string {
+	t.Helper()
+
+	h := sha512.New()
+	for _, leaf := range leaves {
+		h.Write([]byte(leaf))
+	}
+
+	return testSopsEncrypt(t, dataKey, fmt.Sprintf("%X", h.Sum(nil)), lastModified)
+}
@@ -1,12 +1,16 @@
 package encryptedssm
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/hashicorp/aws-sdk-go-base/tfawserr"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -65,6 +69,39 @@ func (tags KeyValueTags) IgnorePrefixes(ignoreTagPrefixes KeyValueTags) KeyValue
 	return result
 }
 
+// Merge adds missing and updates existing tags.
+func (tags KeyValueTags) Merge(mergeTags KeyValueTags) KeyValueTags {
+	result := make(KeyValueTags)
+
+	for k, v := range tags {
+		result[k] = v
+	}
+
+	for k, v := range mergeTags {
+		result[k] = v
+	}
+
+	return result
+}
+
+// RemoveDefaultConfig returns tags not present in a DefaultConfig object
+// in addition to tags with key/value pairs that override those in a DefaultConfig.
+func (tags KeyValueTags) RemoveDefaultConfig(dc *DefaultConfig) KeyValueTags {
+	if dc == nil || dc.Tags == nil {
+		return tags
+	}
+
+	result := make(KeyValueTags)
+
+	for k, v := range tags {
+		if defaultVal, ok := dc.Tags[k]; !ok || !v.Equal(defaultVal) {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
 // Map returns tag keys mapped to their values.
 func (tags KeyValueTags) Map() map[string]string {
 	result := make(map[string]string, len(tags))
@@ -99,7 +136,7 @@ func (tags KeyValueTags) Ignore(ignoreTags KeyValueTags) KeyValueTags {
 // SsmListTags lists ssm service tags.
 // The identifier is typically the Amazon Resource Name (ARN), although
 // it may also be a different identifier depending on the service.
-func SsmListTags(conn *ssm.SSM, identifier string, resourceType string) (KeyValueTags, error) {
+func SsmListTags(conn ssmiface.SSMAPI, identifier string, resourceType string) (KeyValueTags, error) {
 	input := &ssm.ListTagsForResourceInput{
 		ResourceId:   aws.String(identifier),
 		ResourceType: aws.String(resourceType),
@@ -125,6 +162,17 @@ func SsmKeyValueTags(tags []*ssm.Tag) KeyValueTags {
 	return New(m)
 }
 
+// SecretsmanagerKeyValueTags creates KeyValueTags from secretsmanager service tags.
+func SecretsmanagerKeyValueTags(tags []*secretsmanager.Tag) KeyValueTags {
+	m := make(map[string]*string, len(tags))
+
+	for _, tag := range tags {
+		m[aws.StringValue(tag.Key)] = tag.Value
+	}
+
+	return New(m)
+}
+
 // New creates KeyValueTags from common Terraform Provider SDK types.
 // Supports map[string]string, map[string]*string, map[string]interface{}, and []interface{}.
 // When passed []interface{}, all elements are treated as keys and assigned nil values.
@@ -196,9 +244,10 @@ func New(i interface{}) KeyValueTags {
 // SsmUpdateTags updates ssm service tags.
 // The identifier is typically the Amazon Resource Name (ARN), although
 // it may also be a different identifier depending on the service.
-func SsmUpdateTags(conn *ssm.SSM, identifier string, resourceType string, oldTagsMap interface{}, newTagsMap interface{}) error {
-	oldTags := New(oldTagsMap)
-	newTags := New(newTagsMap)
+// Tags matching ignoreConfig are never added or removed.
+func SsmUpdateTags(conn ssmiface.SSMAPI, identifier string, resourceType string, oldTagsMap interface{}, newTagsMap interface{}, ignoreConfig *IgnoreConfig) error {
+	oldTags := New(oldTagsMap).IgnoreConfig(ignoreConfig)
+	newTags := New(newTagsMap).IgnoreConfig(ignoreConfig)
 
 	if removedTags := oldTags.Removed(newTags); len(removedTags) > 0 {
 		input := &ssm.RemoveTagsFromResourceInput{
@@ -231,6 +280,43 @@ func SsmUpdateTags(conn *ssm.SSM, identifier string, resourceType string, oldTag
 	return nil
 }
 
+// SecretsmanagerUpdateTags updates secretsmanager service tags.
+// The identifier is typically the Amazon Resource Name (ARN), although
+// it may also be a different identifier depending on the service.
+// Tags matching ignoreConfig are never added or removed.
+func SecretsmanagerUpdateTags(conn secretsmanageriface.SecretsManagerAPI, identifier string, oldTagsMap interface{}, newTagsMap interface{}, ignoreConfig *IgnoreConfig) error {
+	oldTags := New(oldTagsMap).IgnoreConfig(ignoreConfig)
+	newTags := New(newTagsMap).IgnoreConfig(ignoreConfig)
+
+	if removedTags := oldTags.Removed(newTags); len(removedTags) > 0 {
+		input := &secretsmanager.UntagResourceInput{
+			SecretId: aws.String(identifier),
+			TagKeys:  aws.StringSlice(removedTags.IgnoreAws().Keys()),
+		}
+
+		_, err := conn.UntagResource(input)
+
+		if err != nil {
+			return fmt.Errorf("error untagging resource (%s): %w", identifier, err)
+		}
+	}
+
+	if updatedTags := oldTags.Updated(newTags); len(updatedTags) > 0 {
+		input := &secretsmanager.TagResourceInput{
+			SecretId: aws.String(identifier),
+			Tags:     updatedTags.IgnoreAws().SecretsmanagerTags(),
+		}
+
+		_, err := conn.TagResource(input)
+
+		if err != nil {
+			return fmt.Errorf("error tagging resource (%s): %w", identifier, err)
+		}
+	}
+
+	return nil
+}
+
 // Keys returns tag keys.
 func (tags KeyValueTags) Keys() []string {
 	result := make([]string, 0, len(tags))
@@ -258,6 +344,22 @@ func (tags KeyValueTags) SsmTags() []*ssm.Tag {
 	return result
 }
 
+// SecretsmanagerTags returns secretsmanager service tags.
+func (tags KeyValueTags) SecretsmanagerTags() []*secretsmanager.Tag {
+	result := make([]*secretsmanager.Tag, 0, len(tags))
+
+	for k, v := range tags.Map() {
+		tag := &secretsmanager.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		}
+
+		result = append(result, tag)
+	}
+
+	return result
+}
+
 // Removed returns tags removed.
 func (tags KeyValueTags) Removed(newTags KeyValueTags) KeyValueTags {
 	result := make(KeyValueTags)
@@ -332,6 +434,40 @@ func isResourceTimeoutError(err error) bool {
 	return ok && timeoutErr.LastError == nil
 }
 
+// expandStringList converts a list of interface{} strings into []string.
+func expandStringList(configured []interface{}) []string {
+	result := make([]string, 0, len(configured))
+
+	for _, v := range configured {
+		if val, ok := v.(string); ok {
+			result = append(result, val)
+		}
+	}
+
+	return result
+}
+
+// expandStringMap converts a map of interface{} strings into map[string]string.
+func expandStringMap(configured map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(configured))
+
+	for k, v := range configured {
+		if val, ok := v.(string); ok {
+			result[k] = val
+		}
+	}
+
+	return result
+}
+
+// resourceGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff so value helpers can be shared with CustomizeDiff.
+type resourceGetter interface {
+	Get(key string) interface{}
+	GetOk(key string) (interface{}, bool)
+	GetChange(key string) (interface{}, interface{})
+}
+
 // tagsSchema returns the schema to use for tags.
 //
 func tagsSchema() *schema.Schema {
@@ -341,3 +477,45 @@ func tagsSchema() *schema.Schema {
 		Elem:     &schema.Schema{Type: schema.TypeString},
 	}
 }
+
+// tagsSchemaComputed returns the schema to use for tags_all, the resource
+// tags merged with the provider default_tags.
+func tagsSchemaComputed() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeMap,
+		Optional: true,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+}
+
+// SetTagsDiff sets the new plan difference for tags_all, the merge of the
+// provider default_tags and the resource tags.
+func SetTagsDiff(_ context.Context, diff *schema.ResourceDiff, meta interface{}) error {
+	defaultTagsConfig := meta.(*AWSClient).DefaultTagsConfig
+	ignoreTagsConfig := meta.(*AWSClient).IgnoreTagsConfig
+
+	resourceTags := New(diff.Get("tags").(map[string]interface{}))
+
+	allTags := defaultTagsConfig.MergeTags(resourceTags).IgnoreConfig(ignoreTagsConfig)
+
+	// To ensure "tags_all" is correctly computed, we explicitly set the attribute diff
+	// when the merger of resource-level tags onto provider-level tags results in n > 0 tags,
+	// otherwise we mark the attribute as "Computed" only when their is a known diff (excluding an empty map)
+	// or a change for "tags_all".
+	if len(allTags) > 0 {
+		if err := diff.SetNew("tags_all", allTags.Map()); err != nil {
+			return fmt.Errorf("error setting new tags_all diff: %w", err)
+		}
+	} else if len(diff.Get("tags_all").(map[string]interface{})) > 0 {
+		if err := diff.SetNewComputed("tags_all"); err != nil {
+			return fmt.Errorf("error setting tags_all to computed: %w", err)
+		}
+	} else if diff.HasChange("tags_all") {
+		if err := diff.SetNewComputed("tags_all"); err != nil {
+			return fmt.Errorf("error setting tags_all to computed: %w", err)
+		}
+	}
+
+	return nil
+}
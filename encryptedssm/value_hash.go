@@ -0,0 +1,85 @@
+package encryptedssm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// Values are compared through an HMAC-SHA256 rather than in plaintext so the
+// hash recorded in state reveals nothing about the value. The HMAC key is a
+// KMS data key, only the KMS ciphertext of which is kept in state.
+
+// generateValueHashKey returns the base64 KMS ciphertext of a new HMAC key
+// generated under the given symmetric KMS key.
+func generateValueHashKey(keyId string, meta interface{}) (string, error) {
+	kmsconn := meta.(*AWSClient).kmsconn
+
+	resp, err := kmsconn.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(keyId),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error generating value hash key with KMS key (%s): %w", keyId, err)
+	}
+
+	return base64.StdEncoding.EncodeToString(resp.CiphertextBlob), nil
+}
+
+// decryptValueHashKey returns the plaintext HMAC key from its base64 KMS
+// ciphertext.
+func decryptValueHashKey(hashKey string, meta interface{}) ([]byte, error) {
+	plaintext, _, err := decryptValueHashKeyWithArn(hashKey, meta)
+	return plaintext, err
+}
+
+// decryptValueHashKeyWithArn returns the plaintext HMAC key and the ARN of the
+// KMS key it was generated under.
+func decryptValueHashKeyWithArn(hashKey string, meta interface{}) ([]byte, string, error) {
+	blob, err := base64.StdEncoding.DecodeString(hashKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decoding value hash key: %w", err)
+	}
+
+	result, err := kmsDecrypt(&kms.DecryptInput{CiphertextBlob: blob}, meta)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decrypting value hash key: %s", err)
+	}
+
+	return result.Plaintext, aws.StringValue(result.KeyId), nil
+}
+
+// valueHash returns the hex encoded HMAC-SHA256 of value.
+func valueHash(hashKey []byte, value string) string {
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// valueHashes returns the hex encoded HMAC-SHA256 of each value.
+func valueHashes(hashKey []byte, values []string) []string {
+	result := make([]string, 0, len(values))
+
+	for _, v := range values {
+		result = append(result, valueHash(hashKey, v))
+	}
+
+	return result
+}
+
+// valueHashMap returns the hex encoded HMAC-SHA256 of each value by key.
+func valueHashMap(hashKey []byte, values map[string]string) map[string]string {
+	result := make(map[string]string, len(values))
+
+	for k, v := range values {
+		result[k] = valueHash(hashKey, v)
+	}
+
+	return result
+}
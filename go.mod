@@ -3,10 +3,17 @@ module terraform-provider-encryptedssm
 go 1.13
 
 require (
+	filippo.io/age v1.0.0
 	github.com/aws/aws-sdk-go v1.37.4
 	github.com/hashicorp/aws-sdk-go-base v0.7.0
+	github.com/hashicorp/hcl/v2 v2.3.0
+	github.com/hashicorp/terraform-json v0.8.0
 	github.com/hashicorp/terraform-plugin-sdk/v2 v2.4.3
+	github.com/mitchellh/go-homedir v1.1.0
 	//github.com/terraform-providers/terraform-provider-aws v1.60.0
 	github.com/terraform-providers/terraform-provider-aws v1.60.1-0.20210223022959-81a4663225fd
+	github.com/zclconf/go-cty v1.2.1
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
+	gopkg.in/yaml.v2 v2.3.0
 
 )